@@ -17,38 +17,48 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
+	"log/slog"
 	"math"
-	"math/rand"
-	"runtime"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/qcserestipy/gohpc/pkg/workerpool"
-	"github.com/sirupsen/logrus"
 )
 
+var logLevel = new(slog.LevelVar)
+
 func init() {
-	formatter := &logrus.TextFormatter{}
-	formatter.FullTimestamp = true
-	formatter.TimestampFormat = time.RFC3339
-	logrus.SetLevel(logrus.DebugLevel)
-	logrus.SetFormatter(formatter)
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel})))
 }
 
 func main() {
-	logrus.Info("Starting Monte Carlo π approximation")
+	slog.Info("Starting Monte Carlo π approximation")
 	numbPtr := flag.Int("n", 10000000000, "Number of Trials")
+	seedPtr := flag.Uint64("seed", 1, "Master seed of the random number streams")
+	tasksPtr := flag.Int("tasks", 1024, "Number of tasks to split the trials into")
+	verbosePtr := flag.Bool("v", false, "Log pool lifecycle events")
 	flag.Parse()
+	if *verbosePtr {
+		logLevel.Set(slog.LevelDebug)
+	}
 	nTests := *numbPtr
-	logrus.Infof("Number of Trials: %d", nTests)
+	slog.Info("Number of trials", "n", nTests)
 
-	// Split work into tasks
-	numWorkers := runtime.NumCPU()
-	logrus.Infof("System: %d CPU cores available", numWorkers)
+	numWorkers := workerpool.DefaultWorkers()
+	slog.Info("CPU cores available", "cores", numWorkers)
 
+	// Split work into a fixed number of tasks, independent of the worker
+	// count so that a given seed reproduces the same estimate on any
+	// machine, and fine enough that an interrupted run has completed tasks
+	// to report
 	type Task struct{ Count int }
-	tasks := make([]Task, numWorkers)
-	chunk := nTests / numWorkers
-	remainder := nTests % numWorkers
+	numTasks := *tasksPtr
+	tasks := make([]Task, numTasks)
+	chunk := nTests / numTasks
+	remainder := nTests % numTasks
 
 	var totalAllocated int
 	for i := range tasks {
@@ -60,27 +70,29 @@ func main() {
 		totalAllocated += tasks[i].Count
 	}
 
-	logrus.WithFields(logrus.Fields{
-		"workers":         numWorkers,
-		"points_per_task": chunk,
-		"remainder":       remainder,
-		"total_allocated": totalAllocated,
-	}).Info("Work distribution prepared")
+	slog.Info("Work distribution prepared",
+		"workers", numWorkers,
+		"tasks", numTasks,
+		"seed", *seedPtr,
+		"points_per_task", chunk,
+		"remainder", remainder,
+		"total_allocated", totalAllocated,
+	)
 
 	setupStart := time.Now()
-	// Create pool
+	// Create pool; every task draws from its own stream of the master seed
 	pool := workerpool.New[Task, float64](
 		workerpool.WithWorkers(numWorkers),
+		workerpool.WithSeed(*seedPtr),
+		workerpool.WithProgress(workerpool.ProgressBar(os.Stderr)),
+		workerpool.WithLogger(slog.Default()),
 	)
-	logrus.Infof("Worker pool initialized in %v", time.Since(setupStart))
+	slog.Info("Worker pool initialized", "duration", time.Since(setupStart))
 
 	// Define work
 	work := func(ctx context.Context, t Task) float64 {
-		taskStart := time.Now()
 		inCircle := 0
-		seed := time.Now().UnixNano()
-		r := rand.New(rand.NewSource(seed))
-		logrus.Debugf("Worker started with seed %d for %d points", seed, t.Count)
+		r := workerpool.Rand(ctx)
 
 		for i := 0; i < t.Count; i++ {
 			x, y := r.Float64(), r.Float64()
@@ -88,44 +100,44 @@ func main() {
 				inCircle++
 			}
 		}
-
-		ratio := float64(inCircle) / float64(t.Count)
-		logrus.WithFields(logrus.Fields{
-			"points_processed": t.Count,
-			"points_in_circle": inCircle,
-			"local_ratio":      ratio,
-			"local_pi_approx":  4 * ratio,
-			"duration":         time.Since(taskStart),
-		}).Debug("Worker completed")
-
 		return float64(inCircle)
 	}
 
-	// Run
-	ctx, cancel := context.WithCancel(context.Background())
+	// Run until done or interrupted
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 	start := time.Now()
-	logrus.Info("Starting computation...")
-	results, err := pool.Run(ctx, tasks, work)
+	slog.Info("Starting computation")
+	partial, err := pool.RunPartial(ctx, tasks, work)
 	if err != nil {
-		logrus.Fatalf("Worker pool exited with error: %v", err)
+		slog.Warn("Computation interrupted", "err", err)
 	}
 	elapsed := time.Since(start)
 
-	// Aggregate
+	// Aggregate over the tasks that completed
 	total := 0.0
-	for _, v := range results {
-		total += v
+	points := 0
+	for i, v := range partial.Results {
+		if partial.Done.Has(i) {
+			total += v
+			points += tasks[i].Count
+		}
 	}
+	if points == 0 {
+		slog.Error("No task completed, nothing to report")
+		os.Exit(1)
+	}
+	nTests = points
 	piApprox := 4 * (total / float64(nTests))
 
-	logrus.WithFields(logrus.Fields{
-		"pi_approximation": piApprox,
-		"error":            math.Abs(piApprox - math.Pi),
-		"duration":         elapsed,
-		"points_per_sec":   float64(nTests) / elapsed.Seconds(),
-	}).Info("Computation completed")
+	slog.Info("Computation completed",
+		"pi_approximation", piApprox,
+		"error", math.Abs(piApprox-math.Pi),
+		"duration", elapsed,
+		"points_per_sec", float64(nTests)/elapsed.Seconds(),
+		"completed", partial.Fraction(),
+	)
 
-	logrus.Infof("π ≈ %0.8f (error: %0.8f, computed in %s)",
+	fmt.Printf("π ≈ %0.8f (error: %0.8f, computed in %s)\n",
 		piApprox, math.Abs(piApprox-math.Pi), elapsed)
 }
@@ -0,0 +1,235 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package chanutil provides generic channel combinators for building
+// fan-in and fan-out around worker pools.
+//
+// Every combinator starts goroutines that exit, closing their output
+// channels, once their inputs are closed or ctx is cancelled, so no
+// goroutine outlives a cancelled context. After cancellation the
+// combinators stop reading their inputs; producers must observe ctx
+// themselves rather than block on a send forever. Goroutines that range over
+// a combinator's output may simply stop reading once ctx is cancelled.
+package chanutil
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// OrDone returns a channel that carries the values of in until in is closed
+// or ctx is cancelled, so that a plain range loop also stops on
+// cancellation.
+func OrDone[T any](ctx context.Context, in <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok || !send(ctx, out, v) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Merge returns a channel that carries the values of all ins, in no
+// particular order, and is closed once every input is closed or ctx is
+// cancelled.
+func Merge[T any](ctx context.Context, ins ...<-chan T) <-chan T {
+	out := make(chan T)
+	var wg sync.WaitGroup
+	wg.Add(len(ins))
+	for _, in := range ins {
+		go func() {
+			defer wg.Done()
+			for v := range OrDone(ctx, in) {
+				if !send(ctx, out, v) {
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// Tee returns two channels that both carry every value of in. Each value is
+// delivered to both outputs, in either order, before the next one is read,
+// so the pair advances at the pace of the slower consumer.
+func Tee[T any](ctx context.Context, in <-chan T) (<-chan T, <-chan T) {
+	out1, out2 := make(chan T), make(chan T)
+	go func() {
+		defer close(out1)
+		defer close(out2)
+		for v := range OrDone(ctx, in) {
+			// Disable each output once it has its copy
+			o1, o2 := out1, out2
+			for o1 != nil || o2 != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case o1 <- v:
+					o1 = nil
+				case o2 <- v:
+					o2 = nil
+				}
+			}
+		}
+	}()
+	return out1, out2
+}
+
+// Broadcast returns n channels that each carry every value of in. Like Tee,
+// every value is delivered to all outputs, in any order, before the next one
+// is read.
+func Broadcast[T any](ctx context.Context, in <-chan T, n int) []<-chan T {
+	outs := make([]chan T, n)
+	result := make([]<-chan T, n)
+	for i := range outs {
+		outs[i] = make(chan T)
+		result[i] = outs[i]
+	}
+	go func() {
+		defer func() {
+			for _, out := range outs {
+				close(out)
+			}
+		}()
+		// Case 0 watches ctx, case i+1 sends to output i
+		cases := make([]reflect.SelectCase, n+1)
+		cases[0] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())}
+		for v := range OrDone(ctx, in) {
+			value := reflect.ValueOf(&v).Elem()
+			for i, out := range outs {
+				cases[i+1] = reflect.SelectCase{Dir: reflect.SelectSend, Chan: reflect.ValueOf(out), Send: value}
+			}
+			for pending := n; pending > 0; pending-- {
+				chosen, _, _ := reflect.Select(cases)
+				if chosen == 0 {
+					return
+				}
+				// A nil channel blocks forever, disabling the case
+				cases[chosen].Chan = reflect.Value{}
+			}
+		}
+	}()
+	return result
+}
+
+// Batch groups the values of in into slices of up to size values. A batch
+// is emitted once it is full, once maxWait has passed since its first value
+// was received, or when in is closed. A maxWait of zero or less waits for
+// full batches only. On cancellation the pending batch is dropped.
+func Batch[T any](ctx context.Context, in <-chan T, size int, maxWait time.Duration) <-chan []T {
+	size = max(size, 1)
+	out := make(chan []T)
+	go func() {
+		defer close(out)
+		var (
+			batch    []T
+			timer    *time.Timer
+			deadline <-chan time.Time
+		)
+		flush := func() bool {
+			if timer != nil {
+				timer.Stop()
+				deadline = nil
+			}
+			b := batch
+			batch = nil
+			return len(b) == 0 || send(ctx, out, b)
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-deadline:
+				deadline = nil
+				if !flush() {
+					return
+				}
+			case v, ok := <-in:
+				if !ok {
+					flush()
+					return
+				}
+				if batch == nil {
+					batch = make([]T, 0, size)
+					if maxWait > 0 {
+						if timer == nil {
+							timer = time.NewTimer(maxWait)
+						} else {
+							timer.Reset(maxWait)
+						}
+						deadline = timer.C
+					}
+				}
+				batch = append(batch, v)
+				if len(batch) == size && !flush() {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Throttle passes on the values of in no faster than one per interval. It
+// does not accumulate credit while in is idle, so bursts are never emitted.
+func Throttle[T any](ctx context.Context, in <-chan T, interval time.Duration) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		timer := time.NewTimer(interval)
+		timer.Stop()
+		defer timer.Stop()
+		var next time.Time
+		for v := range OrDone(ctx, in) {
+			if wait := time.Until(next); wait > 0 {
+				timer.Reset(wait)
+				select {
+				case <-ctx.Done():
+					return
+				case <-timer.C:
+				}
+			}
+			if !send(ctx, out, v) {
+				return
+			}
+			next = time.Now().Add(interval)
+		}
+	}()
+	return out
+}
+
+// send delivers v on out unless ctx is cancelled first.
+func send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chanutil
+
+import (
+	"context"
+	"runtime"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// checkLeaks fails the test if goroutines started during it are still
+// running shortly after it ends.
+func checkLeaks(t *testing.T) {
+	t.Helper()
+	before := runtime.NumGoroutine()
+	t.Cleanup(func() {
+		deadline := time.Now().Add(2 * time.Second)
+		for runtime.NumGoroutine() > before {
+			if time.Now().After(deadline) {
+				buf := make([]byte, 1<<16)
+				t.Errorf("%d goroutines leaked:\n%s", runtime.NumGoroutine()-before, buf[:runtime.Stack(buf, true)])
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+}
+
+// values returns a closed channel holding vs.
+func values(vs ...int) <-chan int {
+	ch := make(chan int, len(vs))
+	for _, v := range vs {
+		ch <- v
+	}
+	close(ch)
+	return ch
+}
+
+// collect reads ch until it is closed, failing the test if that takes too
+// long.
+func collect[T any](t *testing.T, ch <-chan T) []T {
+	t.Helper()
+	var got []T
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return got
+			}
+			got = append(got, v)
+		case <-timeout:
+			t.Fatal("channel was not closed")
+		}
+	}
+}
+
+// never returns a channel that is never written to or closed.
+func never() <-chan int {
+	return make(chan int)
+}
+
+func TestOrDone(t *testing.T) {
+	checkLeaks(t)
+	got := collect(t, OrDone(context.Background(), values(1, 2, 3)))
+	if !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("got %v", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := OrDone(ctx, never())
+	cancel()
+	collect(t, out)
+}
+
+func TestMerge(t *testing.T) {
+	checkLeaks(t)
+	got := collect(t, Merge(context.Background(), values(1, 2), values(3), values(4, 5, 6)))
+	slices.Sort(got)
+	if !slices.Equal(got, []int{1, 2, 3, 4, 5, 6}) {
+		t.Errorf("got %v", got)
+	}
+	if got := collect(t, Merge[int](context.Background())); len(got) != 0 {
+		t.Errorf("merging nothing gave %v", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := Merge(ctx, never(), values(1, 2, 3), never())
+	<-out
+	cancel()
+	collect(t, out)
+}
+
+func TestTee(t *testing.T) {
+	checkLeaks(t)
+	out1, out2 := Tee(context.Background(), values(1, 2, 3))
+	var got1, got2 []int
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); got1 = collect(t, out1) }()
+	go func() { defer wg.Done(); got2 = collect(t, out2) }()
+	wg.Wait()
+	if !slices.Equal(got1, []int{1, 2, 3}) || !slices.Equal(got2, []int{1, 2, 3}) {
+		t.Errorf("got %v and %v", got1, got2)
+	}
+
+	// Only one consumer reads; cancellation still releases the other
+	ctx, cancel := context.WithCancel(context.Background())
+	out1, out2 = Tee(ctx, values(1, 2, 3))
+	<-out1
+	cancel()
+	collect(t, out1)
+	collect(t, out2)
+}
+
+func TestBroadcast(t *testing.T) {
+	checkLeaks(t)
+	outs := Broadcast(context.Background(), values(1, 2, 3), 3)
+	got := make([][]int, len(outs))
+	var wg sync.WaitGroup
+	for i, out := range outs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got[i] = collect(t, out)
+		}()
+	}
+	wg.Wait()
+	for i := range got {
+		if !slices.Equal(got[i], []int{1, 2, 3}) {
+			t.Errorf("output %d got %v", i, got[i])
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	outs = Broadcast(ctx, values(1, 2, 3), 2)
+	<-outs[0]
+	cancel()
+	for _, out := range outs {
+		collect(t, out)
+	}
+}
+
+func TestBatchFull(t *testing.T) {
+	checkLeaks(t)
+	got := collect(t, Batch(context.Background(), values(1, 2, 3, 4, 5, 6), 3, time.Hour))
+	if len(got) != 2 || !slices.Equal(got[0], []int{1, 2, 3}) || !slices.Equal(got[1], []int{4, 5, 6}) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestBatchInputClosed(t *testing.T) {
+	checkLeaks(t)
+	got := collect(t, Batch(context.Background(), values(1, 2, 3, 4, 5), 3, 0))
+	if len(got) != 2 || !slices.Equal(got[1], []int{4, 5}) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestBatchMaxWait(t *testing.T) {
+	checkLeaks(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	in := make(chan int)
+	out := Batch(ctx, in, 10, 10*time.Millisecond)
+	for _, v := range []int{1, 2} {
+		in <- v
+	}
+	select {
+	case b := <-out:
+		if !slices.Equal(b, []int{1, 2}) {
+			t.Errorf("got %v", b)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("partial batch was not flushed after maxWait")
+	}
+	// The timer restarts with the next batch
+	in <- 3
+	if b := <-out; !slices.Equal(b, []int{3}) {
+		t.Errorf("got %v", b)
+	}
+	close(in)
+	collect(t, out)
+}
+
+func TestBatchCancel(t *testing.T) {
+	checkLeaks(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int)
+	out := Batch(ctx, in, 10, time.Hour)
+	in <- 1
+	cancel()
+	if got := collect(t, out); len(got) != 0 {
+		t.Errorf("pending batch %v was emitted after cancellation", got)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	checkLeaks(t)
+	const interval = 20 * time.Millisecond
+	out := Throttle(context.Background(), values(1, 2, 3, 4), interval)
+	var times []time.Time
+	for range out {
+		times = append(times, time.Now())
+	}
+	if len(times) != 4 {
+		t.Fatalf("got %d values", len(times))
+	}
+	for i := 1; i < len(times); i++ {
+		// Allow for timer granularity
+		if gap := times[i].Sub(times[i-1]); gap < interval-2*time.Millisecond {
+			t.Errorf("gap %d = %v, want at least %v", i, gap, interval)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out = Throttle(ctx, values(1, 2, 3), time.Hour)
+	<-out
+	cancel()
+	collect(t, out)
+}
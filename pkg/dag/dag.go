@@ -0,0 +1,305 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dag runs tasks with dependencies on a worker pool. Each task
+// starts as soon as all of its dependencies have succeeded, so independent
+// branches of the graph run in parallel:
+//
+//	g := dag.New()
+//	g.Add("factorials", precompute)
+//	g.Add("series", sumSeries, "factorials")
+//	g.Add("pi", divide, "series")
+//	err := g.Run(ctx, workerpool.WithWorkers(8))
+//
+// Tasks exchange data through the variables they close over; a task may
+// read whatever its dependencies wrote.
+package dag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/qcserestipy/gohpc/pkg/workerpool"
+)
+
+var (
+	// ErrDuplicate is returned by Add for a name that is already taken.
+	ErrDuplicate = errors.New("dag: duplicate task")
+	// ErrUnknownDependency is returned by Validate and Run when a task
+	// depends on a name that was never added.
+	ErrUnknownDependency = errors.New("dag: unknown dependency")
+	// ErrSkipped is wrapped by the NodeError of tasks that did not run
+	// because one of their dependencies failed.
+	ErrSkipped = errors.New("dag: dependency failed")
+)
+
+// CycleError is returned by Validate and Run when the dependencies form a
+// cycle.
+type CycleError struct {
+	// Path lists the tasks of the cycle, starting and ending with the same
+	// task.
+	Path []string
+}
+
+func (e *CycleError) Error() string {
+	return "dag: cycle " + strings.Join(e.Path, " -> ")
+}
+
+// NodeError reports the failure of a task.
+type NodeError struct {
+	Name string
+	Err  error
+}
+
+func (e *NodeError) Error() string {
+	return fmt.Sprintf("task %q: %v", e.Name, e.Err)
+}
+
+func (e *NodeError) Unwrap() error {
+	return e.Err
+}
+
+// nodePanic reports a panic recovered from a task without the stream index
+// of its *workerpool.TaskPanicError, which errors.As still finds.
+type nodePanic struct {
+	*workerpool.TaskPanicError
+}
+
+func (e nodePanic) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+func (e nodePanic) Unwrap() error {
+	return e.TaskPanicError
+}
+
+type node struct {
+	name string
+	fn   func(ctx context.Context) error
+	deps []string
+}
+
+// Graph is a set of named tasks and their dependencies. A Graph can be run
+// any number of times but must not be modified while it runs.
+type Graph struct {
+	nodes map[string]*node
+	order []string // insertion order, for deterministic output
+}
+
+// New returns an empty Graph.
+func New() *Graph {
+	return &Graph{nodes: make(map[string]*node)}
+}
+
+// Add registers the task name, which runs fn once every task in deps has
+// succeeded. Dependencies may be added later, but must exist by the time the
+// graph is validated or run.
+func (g *Graph) Add(name string, fn func(ctx context.Context) error, deps ...string) error {
+	if _, ok := g.nodes[name]; ok {
+		return fmt.Errorf("%w %q", ErrDuplicate, name)
+	}
+	g.nodes[name] = &node{name: name, fn: fn, deps: deps}
+	g.order = append(g.order, name)
+	return nil
+}
+
+// Validate checks that every dependency exists and that the graph has no
+// cycles.
+func (g *Graph) Validate() error {
+	for _, name := range g.order {
+		for _, dep := range g.nodes[name].deps {
+			if _, ok := g.nodes[dep]; !ok {
+				return fmt.Errorf("%w %q of task %q", ErrUnknownDependency, dep, name)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(g.nodes))
+	var stack []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			// name is on the stack: the cycle is the stack from there on
+			i := len(stack) - 1
+			for stack[i] != name {
+				i--
+			}
+			path := append(append([]string(nil), stack[i:]...), name)
+			return &CycleError{Path: path}
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, dep := range g.nodes[name].deps {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = visited
+		return nil
+	}
+	for _, name := range g.order {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run validates the graph and executes its tasks on a pool configured by
+// opts, starting every task as soon as its dependencies have succeeded. It
+// returns once every started task has finished.
+//
+// A failed task, including one that panicked, is reported as a *NodeError
+// and its dependents, direct and indirect, are skipped. Under the FailFast
+// policy Run cancels the tasks in flight and returns the first failure;
+// under CollectAll the independent branches keep running and Run returns
+// the failures and skips of all tasks joined together, in the order the
+// tasks were added. If ctx is cancelled, Run returns ctx.Err().
+func (g *Graph) Run(ctx context.Context, opts ...workerpool.PoolOptionFunc) error {
+	if err := g.Validate(); err != nil {
+		return err
+	}
+	if len(g.nodes) == 0 {
+		return nil
+	}
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	waiting := make(map[string]int, len(g.nodes))
+	dependents := make(map[string][]string, len(g.nodes))
+	for _, name := range g.order {
+		n := g.nodes[name]
+		waiting[name] = len(n.deps)
+		for _, dep := range n.deps {
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+
+	// Every task is sent at most once, so sends never block
+	ready := make(chan *node, len(g.nodes))
+	var sent []*node
+	submit := func(n *node) {
+		sent = append(sent, n)
+		ready <- n
+	}
+	for _, name := range g.order {
+		if waiting[name] == 0 {
+			submit(g.nodes[name])
+		}
+	}
+
+	w := workerpool.New[*node, struct{}](opts...)
+	results := w.RunChan(ctx, ready, func(ctx context.Context, n *node) (struct{}, error) {
+		return struct{}{}, n.fn(ctx)
+	})
+
+	failures := make(map[string]error)
+	var first error
+	remaining := len(g.nodes)
+	// skip marks the dependents of a failed task, recursively
+	var skip func(name, cause string)
+	skip = func(name, cause string) {
+		for _, d := range dependents[name] {
+			if _, ok := failures[d]; ok {
+				continue
+			}
+			failures[d] = &NodeError{Name: d, Err: fmt.Errorf("%w: %q", ErrSkipped, cause)}
+			remaining--
+			skip(d, cause)
+		}
+	}
+
+	for r := range results {
+		n := sent[r.Index]
+		remaining--
+		if r.Err != nil {
+			err := r.Err
+			// Strip the stream index, which means nothing to callers
+			var taskErr *workerpool.TaskError
+			var panicErr *workerpool.TaskPanicError
+			switch {
+			case errors.As(err, &taskErr):
+				err = taskErr.Err
+			case errors.As(err, &panicErr):
+				err = nodePanic{panicErr}
+			}
+			failures[n.name] = &NodeError{Name: n.name, Err: err}
+			if first == nil {
+				first = failures[n.name]
+			}
+			skip(n.name, n.name)
+			if w.ErrorPolicy == workerpool.FailFast {
+				cancel()
+			}
+		} else {
+			for _, d := range dependents[n.name] {
+				waiting[d]--
+				if _, failed := failures[d]; !failed && waiting[d] == 0 {
+					submit(g.nodes[d])
+				}
+			}
+		}
+		if remaining == 0 {
+			close(ready)
+		}
+	}
+
+	switch {
+	case parent.Err() != nil:
+		return parent.Err()
+	case first == nil:
+		return nil
+	case w.ErrorPolicy == workerpool.FailFast:
+		return first
+	}
+	var errs []error
+	for _, name := range g.order {
+		if err, ok := failures[name]; ok {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language, with an edge from
+// every dependency to its dependent.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph dag {\n")
+	for _, name := range g.order {
+		fmt.Fprintf(&b, "\t%s;\n", strconv.Quote(name))
+	}
+	for _, name := range g.order {
+		for _, dep := range g.nodes[name].deps {
+			fmt.Fprintf(&b, "\t%s -> %s;\n", strconv.Quote(dep), strconv.Quote(name))
+		}
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,267 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dag
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/qcserestipy/gohpc/pkg/workerpool"
+)
+
+var errBroken = errors.New("broken")
+
+// recorder logs the tasks that ran.
+type recorder struct {
+	mu  sync.Mutex
+	ran []string
+}
+
+func (r *recorder) task(name string, err error) func(context.Context) error {
+	return func(context.Context) error {
+		r.mu.Lock()
+		r.ran = append(r.ran, name)
+		r.mu.Unlock()
+		return err
+	}
+}
+
+func (r *recorder) sorted() []string {
+	return slices.Sorted(slices.Values(r.ran))
+}
+
+func TestRunOrder(t *testing.T) {
+	var r recorder
+	g := New()
+	// A diamond with a tail, added out of order
+	g.Add("d", r.task("d", nil), "b", "c")
+	g.Add("a", r.task("a", nil))
+	g.Add("b", r.task("b", nil), "a")
+	g.Add("c", r.task("c", nil), "a")
+	g.Add("e", r.task("e", nil), "d")
+	if err := g.Run(context.Background(), workerpool.WithWorkers(4)); err != nil {
+		t.Fatal(err)
+	}
+	pos := make(map[string]int)
+	for i, name := range r.ran {
+		pos[name] = i
+	}
+	if len(r.ran) != 5 {
+		t.Fatalf("ran %v, want every task once", r.ran)
+	}
+	for _, edge := range [][2]string{{"a", "b"}, {"a", "c"}, {"b", "d"}, {"c", "d"}, {"d", "e"}} {
+		if pos[edge[0]] > pos[edge[1]] {
+			t.Errorf("%s ran before its dependency %s: %v", edge[1], edge[0], r.ran)
+		}
+	}
+}
+
+func TestDuplicate(t *testing.T) {
+	g := New()
+	g.Add("a", nil)
+	if err := g.Add("a", nil); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("err = %v, want ErrDuplicate", err)
+	}
+}
+
+func TestCycle(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(g *Graph, r *recorder)
+		want  []string
+	}{
+		{"loop", func(g *Graph, r *recorder) {
+			g.Add("x", r.task("x", nil), "x")
+		}, []string{"x", "x"}},
+		{"triangle", func(g *Graph, r *recorder) {
+			g.Add("a", r.task("a", nil), "c")
+			g.Add("b", r.task("b", nil), "a")
+			g.Add("c", r.task("c", nil), "b")
+		}, []string{"a", "c", "b", "a"}},
+		{"behind a root", func(g *Graph, r *recorder) {
+			g.Add("root", r.task("root", nil))
+			g.Add("p", r.task("p", nil), "root", "q")
+			g.Add("q", r.task("q", nil), "p")
+		}, []string{"p", "q", "p"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r recorder
+			g := New()
+			tt.build(g, &r)
+			var cycle *CycleError
+			if err := g.Validate(); !errors.As(err, &cycle) || !slices.Equal(cycle.Path, tt.want) {
+				t.Errorf("Validate() = %v, want cycle %v", err, tt.want)
+			}
+			if err := g.Run(context.Background()); !errors.As(err, &cycle) {
+				t.Errorf("Run() = %v, want a *CycleError", err)
+			}
+			if len(r.ran) != 0 {
+				t.Errorf("ran %v in a cyclic graph", r.ran)
+			}
+		})
+	}
+}
+
+func TestUnknownDependency(t *testing.T) {
+	var r recorder
+	g := New()
+	g.Add("a", r.task("a", nil))
+	g.Add("b", r.task("b", nil), "a", "missing")
+	err := g.Validate()
+	if !errors.Is(err, ErrUnknownDependency) || !strings.Contains(err.Error(), `"missing" of task "b"`) {
+		t.Errorf("Validate() = %v, want ErrUnknownDependency naming the tasks", err)
+	}
+	if err := g.Run(context.Background()); !errors.Is(err, ErrUnknownDependency) || len(r.ran) != 0 {
+		t.Errorf("Run() = %v after running %v", err, r.ran)
+	}
+}
+
+func TestCollectAllSkips(t *testing.T) {
+	var r recorder
+	g := New()
+	g.Add("a", r.task("a", errBroken))
+	g.Add("b", r.task("b", nil), "a")
+	g.Add("c", r.task("c", nil), "b")
+	g.Add("d", r.task("d", nil))
+	g.Add("e", r.task("e", nil), "d", "c")
+	g.Add("f", r.task("f", nil), "d")
+	err := g.Run(context.Background(), workerpool.WithWorkers(2), workerpool.WithErrorPolicy(workerpool.CollectAll))
+	if want := []string{"a", "d", "f"}; !slices.Equal(r.sorted(), want) {
+		t.Errorf("ran %v, want %v", r.sorted(), want)
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("err = %v, want the joined failures", err)
+	}
+	errs := joined.Unwrap()
+	var names []string
+	for _, e := range errs {
+		var nodeErr *NodeError
+		if !errors.As(e, &nodeErr) {
+			t.Fatalf("%v is not a *NodeError", e)
+		}
+		names = append(names, nodeErr.Name)
+		if skipped := errors.Is(e, ErrSkipped); skipped != (nodeErr.Name != "a") {
+			t.Errorf("task %s: %v", nodeErr.Name, e)
+		}
+	}
+	if want := []string{"a", "b", "c", "e"}; !slices.Equal(names, want) {
+		t.Fatalf("failed tasks %v, want %v in the order they were added", names, want)
+	}
+	// The skip of e, three levels below the failure, still names its cause
+	if !errors.Is(errs[0], errBroken) || !strings.Contains(errs[3].Error(), `"a"`) {
+		t.Errorf("errors %v", errs)
+	}
+}
+
+func TestFailFastCancels(t *testing.T) {
+	var r recorder
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	g := New()
+	g.Add("slow", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	})
+	g.Add("fail", func(context.Context) error {
+		<-started
+		return errBroken
+	})
+	g.Add("after", r.task("after", nil), "fail")
+	err := g.Run(context.Background(), workerpool.WithWorkers(2))
+	var nodeErr *NodeError
+	if !errors.As(err, &nodeErr) || nodeErr.Name != "fail" || !errors.Is(err, errBroken) {
+		t.Errorf("err = %v, want the failure of task fail", err)
+	}
+	select {
+	case <-cancelled:
+	default:
+		t.Error("the running task was not cancelled")
+	}
+	if slices.Contains(r.ran, "after") {
+		t.Error("the dependent of the failed task ran")
+	}
+}
+
+func TestPanic(t *testing.T) {
+	g := New()
+	g.Add("a", func(context.Context) error { return nil })
+	g.Add("b", func(context.Context) error { panic("boom") }, "a")
+	err := g.Run(context.Background())
+	if want := `task "b": panic: boom`; err == nil || err.Error() != want {
+		t.Errorf("err = %v, want %s", err, want)
+	}
+	var panicErr *workerpool.TaskPanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "boom" {
+		t.Errorf("err = %v does not hold the *TaskPanicError", err)
+	}
+}
+
+func TestErrorStripsIndex(t *testing.T) {
+	g := New()
+	g.Add("a", func(context.Context) error { return errBroken })
+	if err := g.Run(context.Background()); err == nil || err.Error() != `task "a": broken` {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	g := New()
+	g.Add("a", func(context.Context) error {
+		cancel()
+		return nil
+	})
+	g.Add("b", func(context.Context) error { return nil }, "a")
+	if err := g.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestRunEmpty(t *testing.T) {
+	if err := New().Run(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	g := New()
+	g.Add("load", nil)
+	g.Add("fit \"model\"", nil, "load")
+	g.Add("plot", nil, "load", "fit \"model\"")
+	var b strings.Builder
+	if err := g.WriteDOT(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph dag {
+	"load";
+	"fit \"model\"";
+	"plot";
+	"load" -> "fit \"model\"";
+	"load" -> "plot";
+	"fit \"model\"" -> "plot";
+}
+`
+	if b.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", b.String(), want)
+	}
+}
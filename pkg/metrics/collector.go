@@ -0,0 +1,123 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics collects execution metrics from worker pools and exports
+// them in the Prometheus text exposition format, without depending on the
+// Prometheus client libraries.
+//
+// A Collector implements workerpool.Metrics:
+//
+//	c := metrics.NewCollector("sweep")
+//	pool := workerpool.New[In, Out](workerpool.WithMetrics(c))
+//	http.Handle("/metrics", metrics.Handler(c))
+package metrics
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/qcserestipy/gohpc/pkg/workerpool"
+)
+
+var _ workerpool.Metrics = (*Collector)(nil)
+
+// DefaultBuckets are the upper bounds, in seconds, of the task latency
+// histogram, spanning sub-millisecond kernels to hour-long tasks.
+var DefaultBuckets = []float64{0.0001, 0.001, 0.01, 0.1, 0.5, 1, 5, 10, 60, 300, 3600}
+
+// Collector aggregates the events of one or more pools under a common pool
+// label. It is safe for concurrent use.
+type Collector struct {
+	pool string
+
+	started   atomic.Uint64
+	completed atomic.Uint64
+	failed    atomic.Uint64
+	running   atomic.Int64
+	queued    atomic.Int64
+	idle      atomic.Int64 // nanoseconds
+	latency   *Histogram
+}
+
+// NewCollector returns a Collector whose metrics carry the label
+// pool="name", using DefaultBuckets for task latencies.
+func NewCollector(name string) *Collector {
+	return NewCollectorWithBuckets(name, DefaultBuckets)
+}
+
+// NewCollectorWithBuckets is like NewCollector with custom latency bucket
+// bounds in seconds, which must be sorted in increasing order.
+func NewCollectorWithBuckets(name string, buckets []float64) *Collector {
+	return &Collector{pool: name, latency: NewHistogram(buckets)}
+}
+
+func (c *Collector) TaskStarted(worker int) {
+	c.started.Add(1)
+	c.running.Add(1)
+}
+
+func (c *Collector) TaskFinished(worker int, latency time.Duration, err error) {
+	c.running.Add(-1)
+	if err != nil {
+		c.failed.Add(1)
+	} else {
+		c.completed.Add(1)
+	}
+	c.latency.Observe(latency.Seconds())
+}
+
+func (c *Collector) WorkerIdle(worker int, idle time.Duration) {
+	c.idle.Add(int64(idle))
+}
+
+func (c *Collector) QueueDepth(depth int) {
+	c.queued.Store(int64(depth))
+}
+
+// Histogram counts observations in cumulative buckets, like a Prometheus
+// histogram. It is safe for concurrent use.
+type Histogram struct {
+	bounds []float64
+	counts []atomic.Uint64 // one per bound, plus the +Inf bucket
+	sum    atomic.Uint64   // float64 bits
+}
+
+// NewHistogram returns an empty Histogram with the given sorted upper
+// bounds.
+func NewHistogram(bounds []float64) *Histogram {
+	return &Histogram{
+		bounds: bounds,
+		counts: make([]atomic.Uint64, len(bounds)+1),
+	}
+}
+
+// Observe records a single value.
+func (h *Histogram) Observe(v float64) {
+	i := 0
+	for i < len(h.bounds) && v > h.bounds[i] {
+		i++
+	}
+	h.counts[i].Add(1)
+	for {
+		old := h.sum.Load()
+		if h.sum.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+func (h *Histogram) sumValue() float64 {
+	return math.Float64frombits(h.sum.Load())
+}
@@ -0,0 +1,101 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// family is a metric with one sample per collector.
+type family struct {
+	name  string
+	kind  string
+	help  string
+	value func(c *Collector) float64
+}
+
+var families = []family{
+	{"gohpc_tasks_started_total", "counter", "Tasks started by the pool.",
+		func(c *Collector) float64 { return float64(c.started.Load()) }},
+	{"gohpc_tasks_completed_total", "counter", "Tasks that finished successfully.",
+		func(c *Collector) float64 { return float64(c.completed.Load()) }},
+	{"gohpc_tasks_failed_total", "counter", "Tasks that finished with an error or panic.",
+		func(c *Collector) float64 { return float64(c.failed.Load()) }},
+	{"gohpc_tasks_running", "gauge", "Tasks currently running.",
+		func(c *Collector) float64 { return float64(c.running.Load()) }},
+	{"gohpc_queue_depth", "gauge", "Tasks waiting to be started.",
+		func(c *Collector) float64 { return float64(c.queued.Load()) }},
+	{"gohpc_worker_idle_seconds_total", "counter", "Time workers spent waiting for tasks.",
+		func(c *Collector) float64 { return time.Duration(c.idle.Load()).Seconds() }},
+}
+
+// Write renders the metrics of the collectors in the Prometheus text
+// exposition format.
+func Write(w io.Writer, collectors ...*Collector) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+		for _, c := range collectors {
+			fmt.Fprintf(bw, "%s{pool=\"%s\"} %s\n", f.name, escape(c.pool), format(f.value(c)))
+		}
+	}
+
+	const hist = "gohpc_task_duration_seconds"
+	fmt.Fprintf(bw, "# HELP %s Task latency including retries.\n# TYPE %s histogram\n", hist, hist)
+	for _, c := range collectors {
+		h := c.latency
+		pool := escape(c.pool)
+		var cumulative uint64
+		for i, bound := range h.bounds {
+			cumulative += h.counts[i].Load()
+			fmt.Fprintf(bw, "%s_bucket{pool=\"%s\",le=\"%s\"} %d\n", hist, pool, format(bound), cumulative)
+		}
+		cumulative += h.counts[len(h.bounds)].Load()
+		fmt.Fprintf(bw, "%s_bucket{pool=\"%s\",le=\"+Inf\"} %d\n", hist, pool, cumulative)
+		fmt.Fprintf(bw, "%s_sum{pool=\"%s\"} %s\n", hist, pool, format(h.sumValue()))
+		fmt.Fprintf(bw, "%s_count{pool=\"%s\"} %d\n", hist, pool, cumulative)
+	}
+	return bw.Flush()
+}
+
+// Handler returns an http.Handler that serves the metrics of the
+// collectors, to be mounted at the scrape path, usually /metrics.
+func Handler(collectors ...*Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		if err := Write(w, collectors...); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func format(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(s string) string {
+	return labelEscaper.Replace(s)
+}
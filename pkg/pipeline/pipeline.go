@@ -0,0 +1,196 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pipeline composes typed processing stages, each running on its own
+// pool of workers, into a streaming pipeline:
+//
+//	parse := pipeline.NewStage("parse", parseRecord, workerpool.WithWorkers(2))
+//	score := pipeline.NewStage("score", scoreRecord, workerpool.WithWorkers(16), workerpool.WithBuffer(64))
+//	p := pipeline.Then(parse, score)
+//	out, wait := pipeline.Run(ctx, p, records)
+//	for s := range out {
+//		...
+//	}
+//	err := wait()
+//
+// Every stage fans its input out to its workers and fans their results back
+// in before handing them to the next stage, so slow stages can be given more
+// workers than fast ones. Memory stays bounded by the stages' buffer sizes.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"sync"
+
+	"github.com/qcserestipy/gohpc/pkg/workerpool"
+)
+
+// StageError reports an input that failed in a stage. Err is the
+// *workerpool.TaskError or *workerpool.TaskPanicError of the task, whose
+// index counts the inputs of that stage.
+type StageError struct {
+	Stage string
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("stage %s: %v", e.Stage, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// Stage is a step of a pipeline that turns values of type A into values of
+// type B. A Stage is either created by NewStage or composed of other stages
+// by Then. It can be run any number of times.
+type Stage[A any, B any] struct {
+	stats []*stageMetrics
+	start func(r *run, in <-chan A) <-chan B
+}
+
+// run is the state shared by the stages of a single Run.
+type run struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	errs     []error
+	failFast error
+}
+
+// fail records err from a stage, stopping the whole pipeline if the stage
+// runs under FailFast.
+func (r *run) fail(err error, policy workerpool.ErrorPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ctx.Err() != nil {
+		return
+	}
+	if policy == workerpool.FailFast {
+		r.failFast = err
+		r.cancel()
+		return
+	}
+	r.errs = append(r.errs, err)
+}
+
+// NewStage returns a stage named name that applies fn to every value on a
+// pool configured by opts. WithWorkers and WithBuffer set the stage's
+// concurrency and how many values it pulls ahead; WithOrdered makes it emit
+// results in input order, so a pipeline preserves the input order when all
+// of its stages are ordered.
+//
+// The ErrorPolicy of the stage decides what a failure does: under FailFast
+// it stops the whole pipeline, under CollectAll the failed value is dropped
+// and the pipeline goes on.
+func NewStage[A any, B any](name string, fn func(ctx context.Context, a A) (B, error), opts ...workerpool.PoolOptionFunc) Stage[A, B] {
+	w := workerpool.New[A, B](opts...)
+	m := &stageMetrics{name: name, next: w.Metrics}
+	w.Metrics = m
+
+	return Stage[A, B]{
+		stats: []*stageMetrics{m},
+		start: func(r *run, in <-chan A) <-chan B {
+			results := w.RunChan(r.ctx, in, fn)
+			out := make(chan B)
+			r.wg.Add(1)
+			go func() {
+				defer r.wg.Done()
+				defer close(out)
+				// Keep draining after cancellation so that the stage's
+				// workers can exit
+				for res := range results {
+					if res.Err != nil {
+						r.fail(&StageError{Stage: name, Err: res.Err}, w.ErrorPolicy)
+						continue
+					}
+					select {
+					case out <- res.Value:
+					case <-r.ctx.Done():
+					}
+				}
+			}()
+			return out
+		},
+	}
+}
+
+// Then returns the stage that feeds the output of first into second.
+func Then[A any, B any, C any](first Stage[A, B], second Stage[B, C]) Stage[A, C] {
+	stats := make([]*stageMetrics, 0, len(first.stats)+len(second.stats))
+	stats = append(append(stats, first.stats...), second.stats...)
+	return Stage[A, C]{
+		stats: stats,
+		start: func(r *run, in <-chan A) <-chan C {
+			return second.start(r, first.start(r, in))
+		},
+	}
+}
+
+// Run streams inputs through s and returns the channel of its outputs
+// together with a function that waits for the pipeline to shut down and
+// returns its error. The caller must either drain the channel or cancel ctx
+// before calling wait.
+//
+// wait returns ctx.Err() if ctx was cancelled, the first failure of a
+// FailFast stage, or the failures of CollectAll stages joined together as
+// *StageError values.
+func Run[A any, B any](ctx context.Context, s Stage[A, B], inputs iter.Seq[A]) (<-chan B, func() error) {
+	parent := ctx
+	r := &run{}
+	r.ctx, r.cancel = context.WithCancel(ctx)
+
+	in := make(chan A)
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer close(in)
+		for a := range inputs {
+			select {
+			case in <- a:
+			case <-r.ctx.Done():
+				return
+			}
+		}
+	}()
+	out := s.start(r, in)
+
+	wait := func() error {
+		r.wg.Wait()
+		r.cancel()
+		switch {
+		case parent.Err() != nil:
+			return parent.Err()
+		case r.failFast != nil:
+			return r.failFast
+		}
+		return errors.Join(r.errs...)
+	}
+	return out, wait
+}
+
+// Stats returns a snapshot of the metrics of every stage in s, in pipeline
+// order. The counters accumulate over all runs of the stage.
+func (s Stage[A, B]) Stats() []Stats {
+	stats := make([]Stats, len(s.stats))
+	for i, m := range s.stats {
+		stats[i] = m.snapshot()
+	}
+	return stats
+}
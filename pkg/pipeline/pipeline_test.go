@@ -0,0 +1,217 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/qcserestipy/gohpc/pkg/workerpool"
+)
+
+var errBroken = errors.New("broken")
+
+// count yields 0, 1, ... up to n, or forever if n is negative.
+func count(n int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i := 0; n < 0 || i < n; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+// jitter sleeps for a time that depends on x, so that workers finish out of
+// order.
+func jitter(x int) {
+	time.Sleep(time.Duration(x*7%5) * 100 * time.Microsecond)
+}
+
+func double(_ context.Context, x int) (int, error) {
+	jitter(x)
+	return 2 * x, nil
+}
+
+func format(_ context.Context, x int) (string, error) {
+	jitter(x / 2)
+	return strconv.Itoa(x), nil
+}
+
+func TestOrdered(t *testing.T) {
+	p := Then(
+		NewStage("double", double, workerpool.WithWorkers(4), workerpool.WithOrdered()),
+		NewStage("format", format, workerpool.WithWorkers(3), workerpool.WithOrdered()),
+	)
+	out, wait := Run(context.Background(), p, count(200))
+	var got []string
+	for s := range out {
+		got = append(got, s)
+	}
+	if err := wait(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 200 {
+		t.Fatalf("got %d values, want 200", len(got))
+	}
+	for i, s := range got {
+		if s != strconv.Itoa(2*i) {
+			t.Fatalf("value %d = %s, want %d", i, s, 2*i)
+		}
+	}
+}
+
+func TestUnordered(t *testing.T) {
+	p := Then(
+		NewStage("double", double, workerpool.WithWorkers(4)),
+		NewStage("format", format, workerpool.WithWorkers(3)),
+	)
+	out, wait := Run(context.Background(), p, count(100))
+	var got []int
+	for s := range out {
+		v, _ := strconv.Atoi(s)
+		got = append(got, v/2)
+	}
+	if err := wait(); err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, slices.Collect(count(100))) {
+		t.Errorf("got %v, want every input once", got)
+	}
+}
+
+func TestFailFast(t *testing.T) {
+	fail := func(_ context.Context, x int) (int, error) {
+		if x == 50 {
+			return 0, errBroken
+		}
+		return x, nil
+	}
+	p := Then(
+		NewStage("check", fail, workerpool.WithWorkers(2)),
+		NewStage("double", double, workerpool.WithWorkers(2)),
+	)
+	// The inputs never end, so the run only stops because of the failure
+	out, wait := Run(context.Background(), p, count(-1))
+	for range out {
+	}
+	err := wait()
+	var stageErr *StageError
+	if !errors.As(err, &stageErr) || stageErr.Stage != "check" || !errors.Is(err, errBroken) {
+		t.Fatalf("err = %v, want the failure of stage check", err)
+	}
+	var taskErr *workerpool.TaskError
+	if !errors.As(err, &taskErr) || taskErr.Index != 50 {
+		t.Errorf("err = %v, want a *TaskError for input 50 of the stage", err)
+	}
+}
+
+func TestCollectAll(t *testing.T) {
+	evens := func(_ context.Context, x int) (int, error) {
+		if x%2 == 1 {
+			return 0, errBroken
+		}
+		return x, nil
+	}
+	p := Then(
+		NewStage("evens", evens, workerpool.WithWorkers(2), workerpool.WithErrorPolicy(workerpool.CollectAll),
+			workerpool.WithOrdered()),
+		NewStage("double", double, workerpool.WithWorkers(2), workerpool.WithOrdered()),
+	)
+	out, wait := Run(context.Background(), p, count(20))
+	var got []int
+	for v := range out {
+		got = append(got, v)
+	}
+	if want := []int{0, 4, 8, 12, 16, 20, 24, 28, 32, 36}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v without the failed values", got, want)
+	}
+	joined, ok := wait().(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatal("wait() did not return the joined failures")
+	}
+	var indices []int
+	for _, err := range joined.Unwrap() {
+		var stageErr *StageError
+		var taskErr *workerpool.TaskError
+		if !errors.As(err, &stageErr) || stageErr.Stage != "evens" || !errors.As(err, &taskErr) {
+			t.Fatalf("%v is not a *StageError of stage evens", err)
+		}
+		indices = append(indices, taskErr.Index)
+	}
+	slices.Sort(indices)
+	if want := []int{1, 3, 5, 7, 9, 11, 13, 15, 17, 19}; !slices.Equal(indices, want) {
+		t.Errorf("failed inputs %v, want %v", indices, want)
+	}
+}
+
+func TestWaitCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := NewStage("double", double, workerpool.WithWorkers(2))
+	out, wait := Run(ctx, p, count(-1))
+	for range 10 {
+		<-out
+	}
+	cancel()
+	if err := wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestStats(t *testing.T) {
+	evens := func(_ context.Context, x int) (int, error) {
+		time.Sleep(time.Millisecond)
+		if x%2 == 1 {
+			return 0, errBroken
+		}
+		return x, nil
+	}
+	p := Then(
+		NewStage("evens", evens, workerpool.WithWorkers(2), workerpool.WithErrorPolicy(workerpool.CollectAll)),
+		NewStage("double", double, workerpool.WithWorkers(2)),
+	)
+	for run := 1; run <= 2; run++ {
+		out, wait := Run(context.Background(), p, count(20))
+		for range out {
+		}
+		wait()
+
+		stats := p.Stats()
+		if len(stats) != 2 || stats[0].Name != "evens" || stats[1].Name != "double" {
+			t.Fatalf("stats %+v, want one per stage in pipeline order", stats)
+		}
+		// Counters accumulate over runs
+		first, second := stats[0], stats[1]
+		if first.Processed != int64(20*run) || first.Failed != int64(10*run) {
+			t.Errorf("run %d: stage evens processed %d, failed %d", run, first.Processed, first.Failed)
+		}
+		if second.Processed != int64(10*run) || second.Failed != 0 {
+			t.Errorf("run %d: stage double processed %d, failed %d", run, second.Processed, second.Failed)
+		}
+		if first.Running != 0 || second.Running != 0 {
+			t.Errorf("run %d: %d and %d values still running", run, first.Running, second.Running)
+		}
+		if first.Busy < time.Duration(20*run)*time.Millisecond {
+			t.Errorf("run %d: stage evens busy for %v", run, first.Busy)
+		}
+	}
+}
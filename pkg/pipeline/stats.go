@@ -0,0 +1,99 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipeline
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/qcserestipy/gohpc/pkg/workerpool"
+)
+
+// Stats describes the work done by a stage.
+type Stats struct {
+	Name string
+	// Processed is the number of values the stage has finished, including
+	// Failed ones.
+	Processed int64
+	Failed    int64
+	// Running and Queued are the values currently being processed and
+	// waiting for a worker.
+	Running int64
+	Queued  int64
+	// Busy and Idle are the total time the stage's workers spent running
+	// tasks and waiting for input. A stage that is mostly busy is the
+	// bottleneck of the pipeline.
+	Busy time.Duration
+	Idle time.Duration
+}
+
+// stageMetrics collects the Stats of a stage. It forwards every event to
+// the Metrics configured on the stage, if any.
+type stageMetrics struct {
+	name string
+	next workerpool.Metrics
+
+	processed atomic.Int64
+	failed    atomic.Int64
+	running   atomic.Int64
+	queued    atomic.Int64
+	busy      atomic.Int64
+	idle      atomic.Int64
+}
+
+func (m *stageMetrics) TaskStarted(worker int) {
+	m.running.Add(1)
+	if m.next != nil {
+		m.next.TaskStarted(worker)
+	}
+}
+
+func (m *stageMetrics) TaskFinished(worker int, latency time.Duration, err error) {
+	m.running.Add(-1)
+	m.processed.Add(1)
+	if err != nil {
+		m.failed.Add(1)
+	}
+	m.busy.Add(int64(latency))
+	if m.next != nil {
+		m.next.TaskFinished(worker, latency, err)
+	}
+}
+
+func (m *stageMetrics) WorkerIdle(worker int, idle time.Duration) {
+	m.idle.Add(int64(idle))
+	if m.next != nil {
+		m.next.WorkerIdle(worker, idle)
+	}
+}
+
+func (m *stageMetrics) QueueDepth(depth int) {
+	m.queued.Store(int64(depth))
+	if m.next != nil {
+		m.next.QueueDepth(depth)
+	}
+}
+
+func (m *stageMetrics) snapshot() Stats {
+	return Stats{
+		Name:      m.name,
+		Processed: m.processed.Load(),
+		Failed:    m.failed.Load(),
+		Running:   m.running.Load(),
+		Queued:    m.queued.Load(),
+		Busy:      time.Duration(m.busy.Load()),
+		Idle:      time.Duration(m.idle.Load()),
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rng provides fast, reproducible pseudo-random number generators
+// for parallel workloads. Generators are keyed by a master seed and a stream
+// index, such as a task index, so that every task draws from its own
+// sequence and results do not depend on how tasks are spread across workers.
+//
+// The generators implement math/rand/v2's Source interface and can be
+// wrapped with rand.New for the usual distribution helpers. They are not
+// safe for concurrent use and are not suitable for cryptographic purposes.
+package rng
+
+// SplitMix64 is the generator by Steele, Lea and Flood. It is mainly used
+// to expand a single 64-bit seed into the larger state of other generators.
+type SplitMix64 uint64
+
+const golden = 0x9e3779b97f4a7c15
+
+// Uint64 returns the next value in the sequence.
+func (s *SplitMix64) Uint64() uint64 {
+	*s += golden
+	return mix64(uint64(*s))
+}
+
+// mix64 is the SplitMix64 output function, a bijection on uint64 that
+// spreads nearby inputs over the whole range.
+func mix64(z uint64) uint64 {
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
@@ -0,0 +1,110 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rng
+
+import "math/bits"
+
+// Xoshiro256 is the xoshiro256** generator by Blackman and Vigna. It has a
+// period of 2^256-1 and supports jumping ahead by 2^128 and 2^192 steps to
+// split one sequence into non-overlapping subsequences.
+type Xoshiro256 struct {
+	s [4]uint64
+}
+
+// New returns a generator seeded with seed.
+func New(seed uint64) *Xoshiro256 {
+	x := &Xoshiro256{}
+	x.Seed(seed)
+	return x
+}
+
+// NewStream returns the generator for stream index of the master seed.
+// The same seed and index always produce the same sequence.
+func NewStream(seed, index uint64) *Xoshiro256 {
+	x := &Xoshiro256{}
+	x.SeedStream(seed, index)
+	return x
+}
+
+// Seed resets the generator to the state derived from seed.
+func (x *Xoshiro256) Seed(seed uint64) {
+	sm := SplitMix64(seed)
+	for i := range x.s {
+		x.s[i] = sm.Uint64()
+	}
+}
+
+// SeedStream resets the generator to stream index of the master seed.
+// Distinct indices yield unrelated states, so streams can be handed out per
+// task without coordination. Reseeding does not allocate, which makes it
+// cheap to reuse one generator across many tasks.
+func (x *Xoshiro256) SeedStream(seed, index uint64) {
+	x.Seed(mix64(seed) ^ mix64(index+golden))
+}
+
+// Uint64 returns the next pseudo-random value.
+func (x *Xoshiro256) Uint64() uint64 {
+	s := &x.s
+	result := bits.RotateLeft64(s[1]*5, 7) * 9
+	t := s[1] << 17
+	s[2] ^= s[0]
+	s[3] ^= s[1]
+	s[1] ^= s[2]
+	s[0] ^= s[3]
+	s[2] ^= t
+	s[3] = bits.RotateLeft64(s[3], 45)
+	return result
+}
+
+// Float64 returns a pseudo-random value in [0, 1).
+func (x *Xoshiro256) Float64() float64 {
+	return float64(x.Uint64()>>11) * 0x1.0p-53
+}
+
+// Jump advances the generator by 2^128 steps. Calling Jump repeatedly on
+// copies of one generator yields up to 2^128 non-overlapping subsequences.
+func (x *Xoshiro256) Jump() {
+	x.jump([4]uint64{0x180ec6d33cfd0aba, 0xd5a61266f0c9392c, 0xa9582618e03fc9aa, 0x39abdc4529b1661c})
+}
+
+// LongJump advances the generator by 2^192 steps, for splitting at a coarser
+// level than Jump, such as one LongJump per node and one Jump per worker.
+func (x *Xoshiro256) LongJump() {
+	x.jump([4]uint64{0x76e15d3efefdcbbf, 0xc5004e441c522fb3, 0x77710069854ee241, 0x39109bb02acbe635})
+}
+
+// Split returns a copy of the generator and then advances the receiver with
+// Jump, so the two never produce overlapping values.
+func (x *Xoshiro256) Split() *Xoshiro256 {
+	child := *x
+	x.Jump()
+	return &child
+}
+
+func (x *Xoshiro256) jump(poly [4]uint64) {
+	var s [4]uint64
+	for _, word := range poly {
+		for b := 0; b < 64; b++ {
+			if word&(1<<b) != 0 {
+				s[0] ^= x.s[0]
+				s[1] ^= x.s[1]
+				s[2] ^= x.s[2]
+				s[3] ^= x.s[3]
+			}
+			x.Uint64()
+		}
+	}
+	x.s = s
+}
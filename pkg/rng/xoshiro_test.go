@@ -0,0 +1,109 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rng
+
+import "testing"
+
+// The expected values below come from the reference C implementations at
+// https://prng.di.unimi.it.
+
+// reference returns a generator in the state {1, 2, 3, 4}.
+func reference() *Xoshiro256 {
+	return &Xoshiro256{s: [4]uint64{1, 2, 3, 4}}
+}
+
+func expect(t *testing.T, x *Xoshiro256, want []uint64) {
+	t.Helper()
+	for i, w := range want {
+		if got := x.Uint64(); got != w {
+			t.Fatalf("output %d = %d, want %d", i, got, w)
+		}
+	}
+}
+
+func TestXoshiro256KnownAnswer(t *testing.T) {
+	expect(t, reference(), []uint64{
+		11520, 0, 1509978240, 1215971899390074240,
+		1216172134540287360, 607988272756665600, 16172922978634559625, 8476171486693032832,
+	})
+}
+
+func TestXoshiro256Jump(t *testing.T) {
+	x := reference()
+	x.Jump()
+	if want := [4]uint64{0x8c7a153956b5f3d1, 0x701f1a713401d85e, 0x6527f66a65469085, 0x8386b786c4408050}; x.s != want {
+		t.Fatalf("state after Jump = %#x, want %#x", x.s, want)
+	}
+	expect(t, x, []uint64{13534147089533256664, 7126240192422241655, 3805973808039778091, 11547880530658420384})
+}
+
+func TestXoshiro256LongJump(t *testing.T) {
+	x := reference()
+	x.LongJump()
+	if want := [4]uint64{0x096a8eb71295a400, 0xdbf84991e50f4516, 0x534ee745810d2a0e, 0x31655ca1a2215bf1}; x.s != want {
+		t.Fatalf("state after LongJump = %#x, want %#x", x.s, want)
+	}
+}
+
+func TestXoshiro256Split(t *testing.T) {
+	x := reference()
+	child := x.Split()
+	expect(t, child, []uint64{11520, 0, 1509978240})
+	// The parent continues from the jumped state
+	expect(t, x, []uint64{13534147089533256664, 7126240192422241655})
+}
+
+func TestSplitMix64KnownAnswer(t *testing.T) {
+	s := SplitMix64(1234567)
+	for i, want := range []uint64{
+		6457827717110365317, 3203168211198807973, 9817491932198370423,
+		4593380528125082431, 16408922859458223821,
+	} {
+		if got := s.Uint64(); got != want {
+			t.Fatalf("output %d = %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestSeed(t *testing.T) {
+	// Seeding fills the state from SplitMix64, as recommended upstream
+	expect(t, New(42), []uint64{1546998764402558742, 6990951692964543102, 12544586762248559009, 17057574109182124193})
+}
+
+func TestSeedStream(t *testing.T) {
+	a, b := NewStream(1, 0), NewStream(1, 0)
+	if a.Uint64() != b.Uint64() {
+		t.Error("equal seed and index give different streams")
+	}
+	seen := make(map[uint64]bool)
+	for seed := uint64(0); seed < 4; seed++ {
+		for index := uint64(0); index < 256; index++ {
+			v := NewStream(seed, index).Uint64()
+			if seen[v] {
+				t.Fatalf("stream (%d, %d) collides with an earlier one", seed, index)
+			}
+			seen[v] = true
+		}
+	}
+}
+
+func TestFloat64Range(t *testing.T) {
+	x := New(1)
+	for i := 0; i < 10000; i++ {
+		if f := x.Float64(); f < 0 || f >= 1 {
+			t.Fatalf("Float64() = %v, want [0, 1)", f)
+		}
+	}
+}
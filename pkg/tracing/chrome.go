@@ -0,0 +1,156 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracing provides workerpool.Tracer implementations. ChromeTracer
+// records spans in memory and writes them as a Chrome trace-event JSON file,
+// which can be opened offline in Perfetto (ui.perfetto.dev) or
+// chrome://tracing. The otel subpackage forwards spans to OpenTelemetry.
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/qcserestipy/gohpc/pkg/workerpool"
+)
+
+var _ workerpool.Tracer = (*ChromeTracer)(nil)
+
+// ChromeTracer records runs and task attempts as Chrome trace events. Each
+// run is shown as a process with one thread per worker, and every task
+// attempt as a slice on its worker's thread whose arguments carry the input
+// index, attempt and queue wait. Tasks of a Pool, which have no run, are
+// grouped under process 0.
+//
+// Events are kept in memory until WriteTo is called, so a ChromeTracer is
+// meant for profiling sessions rather than long-running services.
+type ChromeTracer struct {
+	start time.Time
+	runs  atomic.Int64
+
+	mu      sync.Mutex
+	events  []event
+	threads map[[2]int]bool
+}
+
+// event is a single entry of the trace-event format. Timestamps and durations
+// are in microseconds.
+type event struct {
+	Name string         `json:"name"`
+	Cat  string         `json:"cat,omitempty"`
+	Ph   string         `json:"ph"`
+	Ts   float64        `json:"ts"`
+	Dur  float64        `json:"dur,omitempty"`
+	Pid  int            `json:"pid"`
+	Tid  int            `json:"tid"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+// NewChromeTracer returns an empty ChromeTracer. Timestamps in the trace are
+// relative to its creation.
+func NewChromeTracer() *ChromeTracer {
+	return &ChromeTracer{start: time.Now(), threads: make(map[[2]int]bool)}
+}
+
+type runKey struct{}
+
+// runTid is the thread of a process that shows the span of the run itself,
+// kept apart from the worker threads, which start at 0.
+const runTid = -1
+
+func (t *ChromeTracer) StartRun(ctx context.Context, total int) (context.Context, workerpool.Span) {
+	pid := int(t.runs.Add(1))
+	args := map[string]any{}
+	if total >= 0 {
+		args["tasks"] = total
+	}
+	t.add(event{Name: "process_name", Ph: "M", Pid: pid, Args: map[string]any{"name": fmt.Sprintf("run %d", pid)}})
+	return context.WithValue(ctx, runKey{}, pid), &chromeSpan{
+		t:     t,
+		start: time.Now(),
+		ev:    event{Name: "run", Cat: "run", Ph: "X", Pid: pid, Tid: runTid, Args: args},
+	}
+}
+
+func (t *ChromeTracer) StartTask(ctx context.Context, task workerpool.TaskInfo) (context.Context, workerpool.Span) {
+	pid, _ := ctx.Value(runKey{}).(int)
+	start := time.Now()
+	args := map[string]any{
+		"index":   task.Index,
+		"attempt": task.Attempt,
+	}
+	if !task.Queued.IsZero() {
+		args["queue_wait_us"] = micros(start.Sub(task.Queued))
+	}
+	return ctx, &chromeSpan{
+		t:     t,
+		start: start,
+		ev:    event{Name: fmt.Sprintf("task %d", task.Index), Cat: "task", Ph: "X", Pid: pid, Tid: task.Worker, Args: args},
+	}
+}
+
+type chromeSpan struct {
+	t     *ChromeTracer
+	start time.Time
+	ev    event
+}
+
+func (s *chromeSpan) End(err error) {
+	s.ev.Ts = micros(s.start.Sub(s.t.start))
+	s.ev.Dur = micros(time.Since(s.start))
+	if err != nil {
+		s.ev.Args["error"] = err.Error()
+	}
+	s.t.add(s.ev)
+}
+
+func (t *ChromeTracer) add(ev event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// Name each thread the first time it shows up
+	if ev.Ph == "X" && !t.threads[[2]int{ev.Pid, ev.Tid}] {
+		t.threads[[2]int{ev.Pid, ev.Tid}] = true
+		name := fmt.Sprintf("worker %d", ev.Tid)
+		if ev.Tid == runTid {
+			name = "run"
+		}
+		t.events = append(t.events, event{Name: "thread_name", Ph: "M", Pid: ev.Pid, Tid: ev.Tid, Args: map[string]any{"name": name}})
+	}
+	t.events = append(t.events, ev)
+}
+
+// WriteTo writes the events recorded so far as a trace-event JSON object.
+// Spans that are still open are not included.
+func (t *ChromeTracer) WriteTo(w io.Writer) (int64, error) {
+	t.mu.Lock()
+	b, err := json.Marshal(struct {
+		TraceEvents     []event `json:"traceEvents"`
+		DisplayTimeUnit string  `json:"displayTimeUnit"`
+	}{t.events, "ms"})
+	t.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+func micros(d time.Duration) float64 {
+	return float64(d) / float64(time.Microsecond)
+}
@@ -0,0 +1,16 @@
+module github.com/qcserestipy/gohpc/pkg/tracing/otel
+
+go 1.23.7
+
+require (
+	github.com/qcserestipy/gohpc v0.0.0-20261016061420-85ef05e3a0ae
+	go.opentelemetry.io/otel v1.36.0
+	go.opentelemetry.io/otel/trace v1.36.0
+)
+
+require golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+
+// The adapter is developed alongside the workerpool it plugs into. The
+// replace only applies when building inside this repository; users of the
+// module get the version required above.
+replace github.com/qcserestipy/gohpc => ../../..
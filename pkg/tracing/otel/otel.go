@@ -0,0 +1,94 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package otel adapts OpenTelemetry tracing to workerpool.Tracer:
+//
+//	pool := workerpool.New[In, Out](workerpool.WithTracer(otel.New(provider)))
+//
+// Runs become "workerpool.run" spans and task attempts "workerpool.task" spans
+// carrying the input index, worker ID, attempt and queue wait as attributes.
+//
+// The package is a module of its own, so that gohpc itself does not depend on
+// OpenTelemetry:
+//
+//	go get github.com/qcserestipy/gohpc/pkg/tracing/otel
+package otel
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/qcserestipy/gohpc/pkg/workerpool"
+)
+
+// ScopeName is the instrumentation scope under which spans are created.
+const ScopeName = "github.com/qcserestipy/gohpc/pkg/workerpool"
+
+// Attribute keys set on the spans.
+const (
+	TasksKey     = attribute.Key("gohpc.run.tasks")
+	IndexKey     = attribute.Key("gohpc.task.index")
+	WorkerKey    = attribute.Key("gohpc.task.worker")
+	AttemptKey   = attribute.Key("gohpc.task.attempt")
+	QueueWaitKey = attribute.Key("gohpc.task.queue_wait_ms")
+)
+
+var _ workerpool.Tracer = (*Tracer)(nil)
+
+// Tracer creates OpenTelemetry spans for the runs and task attempts of a
+// pool.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// New returns a Tracer that creates spans with a tracer from tp.
+func New(tp trace.TracerProvider) *Tracer {
+	return &Tracer{tracer: tp.Tracer(ScopeName)}
+}
+
+func (t *Tracer) StartRun(ctx context.Context, total int) (context.Context, workerpool.Span) {
+	ctx, span := t.tracer.Start(ctx, "workerpool.run", trace.WithAttributes(TasksKey.Int(total)))
+	return ctx, otelSpan{span}
+}
+
+func (t *Tracer) StartTask(ctx context.Context, task workerpool.TaskInfo) (context.Context, workerpool.Span) {
+	start := time.Now()
+	attrs := []attribute.KeyValue{
+		IndexKey.Int(task.Index),
+		WorkerKey.Int(task.Worker),
+		AttemptKey.Int(task.Attempt),
+	}
+	if !task.Queued.IsZero() {
+		wait := start.Sub(task.Queued)
+		attrs = append(attrs, QueueWaitKey.Float64(float64(wait)/float64(time.Millisecond)))
+	}
+	ctx, span := t.tracer.Start(ctx, "workerpool.task", trace.WithTimestamp(start), trace.WithAttributes(attrs...))
+	return ctx, otelSpan{span}
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s otelSpan) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
@@ -0,0 +1,116 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"runtime"
+	"sync"
+)
+
+type affinityKind int
+
+const (
+	unpinned affinityKind = iota
+	compact
+	scatter
+)
+
+// Affinity decides which CPU each worker is pinned to. The zero value leaves
+// workers unpinned.
+//
+// A pinned worker locks its goroutine to an OS thread and restricts that
+// thread to a single CPU for as long as the worker lives, which keeps its
+// caches warm and its memory on the local NUMA node. Pinning is only
+// supported on Linux; elsewhere workers stay unpinned.
+type Affinity struct {
+	kind affinityKind
+}
+
+var (
+	// Compact fills the CPUs of one NUMA node before moving on to the next,
+	// keeping workers close together to share caches and local memory.
+	Compact = Affinity{kind: compact}
+	// Scatter spreads workers round-robin across NUMA nodes, maximising
+	// the aggregate memory bandwidth available to memory-bound kernels.
+	Scatter = Affinity{kind: scatter}
+)
+
+// WithAffinity pins workers to CPUs according to policy. Worker i is pinned
+// to the i-th CPU of the policy's order, wrapping around when there are more
+// workers than CPUs the process may run on.
+func WithAffinity(policy Affinity) PoolOptionFunc {
+	return func(opts *PoolOptions) {
+		opts.Affinity = policy
+	}
+}
+
+// NUMANodes returns the CPUs the process may run on, grouped by NUMA node as
+// listed in /sys/devices/system/node. Without NUMA information all CPUs form
+// a single node. It returns nil where CPU affinity is not supported.
+func NUMANodes() [][]int {
+	return topology()
+}
+
+var topology = sync.OnceValue(cpuTopology)
+
+// order returns the CPUs in the order workers are pinned to them.
+func (a Affinity) order() []int {
+	nodes := topology()
+	var cpus []int
+	switch a.kind {
+	case compact:
+		for _, node := range nodes {
+			cpus = append(cpus, node...)
+		}
+	case scatter:
+		for i := 0; ; i++ {
+			more := false
+			for _, node := range nodes {
+				if i < len(node) {
+					cpus = append(cpus, node[i])
+					more = true
+				}
+			}
+			if !more {
+				break
+			}
+		}
+	}
+	return cpus
+}
+
+// pin binds the calling goroutine, which runs worker, to its CPU and returns
+// the function that undoes it.
+func (o PoolOptions) pin(worker int) (unpin func()) {
+	cpus := o.Affinity.order()
+	if len(cpus) == 0 {
+		return func() {}
+	}
+	cpu := cpus[worker%len(cpus)]
+	runtime.LockOSThread()
+	restore, err := bindThread(cpu)
+	if err != nil {
+		runtime.UnlockOSThread()
+		o.logger().Warn("cannot pin worker", "worker", worker, "cpu", cpu, "err", err)
+		return func() {}
+	}
+	return func() {
+		// A thread whose mask cannot be restored stays locked, so that
+		// it exits with the goroutine instead of running others
+		if restore() == nil {
+			runtime.UnlockOSThread()
+		}
+	}
+}
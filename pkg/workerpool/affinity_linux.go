@@ -0,0 +1,110 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package workerpool
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// cpuTopology returns the CPUs in the affinity mask of the process grouped
+// by NUMA node.
+func cpuTopology() [][]int {
+	var allowed unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &allowed); err != nil {
+		return nil
+	}
+	var nodes [][]int
+	dirs, _ := filepath.Glob("/sys/devices/system/node/node[0-9]*")
+	// Sort numerically so that node10 comes after node9
+	slices.SortFunc(dirs, func(a, b string) int {
+		return nodeID(a) - nodeID(b)
+	})
+	for _, dir := range dirs {
+		b, err := os.ReadFile(filepath.Join(dir, "cpulist"))
+		if err != nil {
+			continue
+		}
+		var node []int
+		for _, cpu := range parseCPUList(string(b)) {
+			if allowed.IsSet(cpu) {
+				node = append(node, cpu)
+			}
+		}
+		if len(node) > 0 {
+			nodes = append(nodes, node)
+		}
+	}
+	if len(nodes) == 0 {
+		var all []int
+		for cpu := 0; len(all) < allowed.Count(); cpu++ {
+			if allowed.IsSet(cpu) {
+				all = append(all, cpu)
+			}
+		}
+		nodes = [][]int{all}
+	}
+	return nodes
+}
+
+func nodeID(dir string) int {
+	id, _ := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "node"))
+	return id
+}
+
+// parseCPUList parses the kernel's list format, such as "0-3,8-11,16".
+func parseCPUList(s string) []int {
+	var cpus []int
+	for _, part := range strings.Split(strings.TrimSpace(s), ",") {
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil {
+			continue
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(hi); err != nil {
+				continue
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus
+}
+
+// bindThread restricts the calling OS thread to cpu and returns the function
+// that restores its previous mask.
+func bindThread(cpu int) (restore func() error, err error) {
+	var old, set unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &old); err != nil {
+		return nil, err
+	}
+	set.Set(cpu)
+	if err := unix.SchedSetaffinity(0, &set); err != nil {
+		return nil, err
+	}
+	return func() error {
+		return unix.SchedSetaffinity(0, &old)
+	}, nil
+}
@@ -0,0 +1,28 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux
+
+package workerpool
+
+import "errors"
+
+// cpuTopology reports no CPUs, which leaves workers unpinned.
+func cpuTopology() [][]int {
+	return nil
+}
+
+func bindThread(cpu int) (func() error, error) {
+	return nil, errors.New("workerpool: CPU affinity is not supported on this platform")
+}
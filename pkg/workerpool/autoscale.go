@@ -0,0 +1,211 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Autoscale lets a pool vary its number of active workers between Min and
+// Max while it runs. The pool starts Max worker goroutines, of which only
+// the active ones take work; the others stay parked until the scaler
+// activates them or the run ends.
+//
+// Every Interval the scaler compares the queue depth with the number of
+// active workers and the throughput with that of the previous interval. It
+// adds a worker while tasks are waiting and the last addition paid off,
+// takes back an addition that did not raise throughput, for instance
+// because the tasks contend on memory bandwidth or a lock, and then waits a
+// few intervals before probing again. It removes workers once the queue
+// runs dry.
+type Autoscale struct {
+	Min, Max int
+	// Interval between scaling decisions. The default is 100ms.
+	Interval time.Duration
+}
+
+// WithAutoscale enables autoscaling between lo and hi active workers and
+// sets NumWorkers to hi. It suits a Dynamic or Guided schedule, streams and
+// the persistent pools; under Static, the block of a parked worker waits
+// until the active workers are done.
+func WithAutoscale(lo, hi int) PoolOptionFunc {
+	return func(opts *PoolOptions) {
+		lo = max(lo, 1)
+		opts.Autoscale = Autoscale{Min: lo, Max: max(hi, lo), Interval: opts.Autoscale.Interval}
+		opts.NumWorkers = opts.Autoscale.Max
+	}
+}
+
+const (
+	// Growth in throughput below which an added worker is taken back
+	scaleGain = 1.05
+	// Intervals to wait after taking back a worker before trying again
+	scaleHold = 10
+)
+
+// scaler activates and parks the workers of a single run or pool. A nil
+// *scaler keeps every worker active.
+type scaler struct {
+	policy    Autoscale
+	log       Logger
+	queued    *atomic.Int64
+	completed atomic.Int64
+
+	mu      sync.Mutex
+	active  int
+	done    bool          // no more work; every worker is let through
+	changed chan struct{} // closed and replaced whenever the above change
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// autoscale starts a scaler over the queue depth counter of a run, or
+// returns nil if autoscaling is off.
+func (o PoolOptions) autoscale(queued *atomic.Int64) *scaler {
+	if o.Autoscale.Max <= 0 {
+		return nil
+	}
+	policy := o.Autoscale
+	policy.Max = min(policy.Max, o.NumWorkers)
+	policy.Min = min(max(policy.Min, 1), policy.Max)
+	if policy.Interval <= 0 {
+		policy.Interval = 100 * time.Millisecond
+	}
+	s := &scaler{
+		policy:  policy,
+		log:     o.logger(),
+		queued:  queued,
+		active:  policy.Min,
+		changed: make(chan struct{}),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go s.loop()
+	return s
+}
+
+func (s *scaler) loop() {
+	defer close(s.stopped)
+	ticker := time.NewTicker(s.policy.Interval)
+	defer ticker.Stop()
+	var (
+		last      float64 // throughput of the previous interval
+		grew      bool    // whether the previous decision added a worker
+		hold      int
+		lastCount int64
+	)
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+		count := s.completed.Load()
+		throughput := float64(count-lastCount) / s.policy.Interval.Seconds()
+		lastCount = count
+		depth := int(s.queued.Load())
+
+		s.mu.Lock()
+		active := s.active
+		if hold > 0 {
+			hold--
+		}
+		switch {
+		case grew && throughput < last*scaleGain:
+			// The last worker did not help
+			active--
+			grew = false
+			hold = scaleHold
+		case depth > active && active < s.policy.Max && hold == 0:
+			active++
+			grew = true
+		case depth == 0 && active > s.policy.Min:
+			active--
+			grew = false
+		default:
+			grew = false
+		}
+		if active != s.active {
+			s.log.Debug("autoscale", "active", active, "queued", depth, "throughput", throughput)
+			s.active = active
+			s.broadcast()
+		}
+		s.mu.Unlock()
+		last = throughput
+	}
+}
+
+// broadcast wakes every parked worker. s.mu must be held.
+func (s *scaler) broadcast() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// await parks worker until it is active, the scaler has been told there is
+// no more work, or ctx is done.
+func (s *scaler) await(ctx context.Context, worker int) {
+	if s == nil {
+		return
+	}
+	for {
+		s.mu.Lock()
+		if worker < s.active || s.done {
+			s.mu.Unlock()
+			return
+		}
+		changed := s.changed
+		s.mu.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// taskDone counts a finished task towards the throughput.
+func (s *scaler) taskDone() {
+	if s != nil {
+		s.completed.Add(1)
+	}
+}
+
+// exhausted releases the parked workers once no more work will arrive, so
+// that they can observe it and exit.
+func (s *scaler) exhausted() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.done {
+		s.done = true
+		s.broadcast()
+	}
+}
+
+// close stops the scaling decisions.
+func (s *scaler) close() {
+	if s == nil {
+		return
+	}
+	s.exhausted()
+	close(s.stop)
+	<-s.stopped
+}
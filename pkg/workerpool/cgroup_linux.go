@@ -0,0 +1,152 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package workerpool
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// cgroupCPULimit returns the CPU quota of the process in CPUs, taken as the
+// tightest limit of its cgroup and the cgroup's ancestors, or false if the
+// process is not limited.
+func cgroupCPULimit() (float64, bool) {
+	groups, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return 0, false
+	}
+	mountinfo, err := os.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		return 0, false
+	}
+	return cpuLimit(string(groups), string(mountinfo))
+}
+
+// cpuLimit resolves the CPU quota from the contents of /proc/self/cgroup and
+// /proc/self/mountinfo.
+func cpuLimit(groups, mountinfo string) (float64, bool) {
+	mounts := parseCgroupMounts(mountinfo)
+	limit := math.Inf(1)
+	// Lines are "hierarchy-ID:controller-list:cgroup-path"
+	for _, line := range strings.Split(strings.TrimSpace(groups), "\n") {
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		v2 := fields[0] == "0" && fields[1] == ""
+		if !v2 && !slices.Contains(strings.Split(fields[1], ","), "cpu") {
+			continue
+		}
+		for _, m := range mounts {
+			if m.v2 != v2 || (!v2 && !m.cpu) {
+				continue
+			}
+			rel, ok := strings.CutPrefix(fields[2], m.root)
+			if !ok || (m.root != "/" && rel != "" && rel[0] != '/') {
+				// Not below the root of this mount
+				continue
+			}
+			// Walk up to the mount point; every level may impose a limit
+			for dir := filepath.Join(m.point, rel); ; dir = filepath.Dir(dir) {
+				if l, ok := readCPULimit(dir, v2); ok {
+					limit = min(limit, l)
+				}
+				if dir == m.point || len(dir) <= len(m.point) {
+					break
+				}
+			}
+		}
+	}
+	if math.IsInf(limit, 1) {
+		return 0, false
+	}
+	return limit, true
+}
+
+type cgroupMount struct {
+	root, point string
+	v2          bool
+	cpu         bool // v1 hierarchy with the cpu controller
+}
+
+// parseCgroupMounts extracts the cgroup file systems from the contents of
+// /proc/self/mountinfo.
+func parseCgroupMounts(mountinfo string) []cgroupMount {
+	var mounts []cgroupMount
+	for _, line := range strings.Split(mountinfo, "\n") {
+		// ID parent major:minor root mount-point options [optional...] - fstype source super-options
+		fields := strings.Fields(line)
+		sep := slices.Index(fields, "-")
+		if sep < 5 || sep+3 >= len(fields) {
+			continue
+		}
+		m := cgroupMount{root: fields[3], point: fields[4]}
+		switch fields[sep+1] {
+		case "cgroup2":
+			m.v2 = true
+		case "cgroup":
+			m.cpu = slices.Contains(strings.Split(fields[sep+3], ","), "cpu")
+		default:
+			continue
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts
+}
+
+// readCPULimit reads the CPU quota of the cgroup at dir in CPUs.
+func readCPULimit(dir string, v2 bool) (float64, bool) {
+	if v2 {
+		// cpu.max holds "$MAX $PERIOD", where $MAX may be "max"
+		b, err := os.ReadFile(filepath.Join(dir, "cpu.max"))
+		if err != nil {
+			return 0, false
+		}
+		fields := strings.Fields(string(b))
+		if len(fields) != 2 || fields[0] == "max" {
+			return 0, false
+		}
+		return quota(fields[0], fields[1])
+	}
+	q, err := os.ReadFile(filepath.Join(dir, "cpu.cfs_quota_us"))
+	if err != nil {
+		return 0, false
+	}
+	p, err := os.ReadFile(filepath.Join(dir, "cpu.cfs_period_us"))
+	if err != nil {
+		return 0, false
+	}
+	return quota(strings.TrimSpace(string(q)), strings.TrimSpace(string(p)))
+}
+
+// quota divides a quota by its period, both in microseconds. A negative
+// quota means no limit.
+func quota(q, period string) (float64, bool) {
+	qv, err := strconv.ParseFloat(q, 64)
+	if err != nil || qv <= 0 {
+		return 0, false
+	}
+	pv, err := strconv.ParseFloat(period, 64)
+	if err != nil || pv <= 0 {
+		return 0, false
+	}
+	return qv / pv, true
+}
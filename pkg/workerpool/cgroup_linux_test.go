@@ -0,0 +1,205 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package workerpool
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// mountLine formats a line of /proc/self/mountinfo.
+func mountLine(root, point, fstype, options string) string {
+	return fmt.Sprintf("30 23 0:26 %s %s rw,nosuid,nodev,noexec,relatime shared:4 - %s %s %s\n",
+		root, point, fstype, fstype, options)
+}
+
+func TestParseCgroupMounts(t *testing.T) {
+	tests := []struct {
+		name      string
+		mountinfo string
+		want      []cgroupMount
+	}{
+		{"v2", mountLine("/", "/sys/fs/cgroup", "cgroup2", "rw,nsdelegate"),
+			[]cgroupMount{{root: "/", point: "/sys/fs/cgroup", v2: true}}},
+		{"v1",
+			mountLine("/", "/sys/fs/cgroup/cpu,cpuacct", "cgroup", "rw,cpu,cpuacct") +
+				mountLine("/", "/sys/fs/cgroup/memory", "cgroup", "rw,memory"),
+			[]cgroupMount{
+				{root: "/", point: "/sys/fs/cgroup/cpu,cpuacct", cpu: true},
+				{root: "/", point: "/sys/fs/cgroup/memory"},
+			}},
+		{"hybrid",
+			mountLine("/", "/sys/fs/cgroup/cpu", "cgroup", "rw,cpu") +
+				mountLine("/", "/sys/fs/cgroup/unified", "cgroup2", "rw"),
+			[]cgroupMount{
+				{root: "/", point: "/sys/fs/cgroup/cpu", cpu: true},
+				{root: "/", point: "/sys/fs/cgroup/unified", v2: true},
+			}},
+		{"namespaced root", mountLine("/docker/abc", "/sys/fs/cgroup", "cgroup2", "rw"),
+			[]cgroupMount{{root: "/docker/abc", point: "/sys/fs/cgroup", v2: true}}},
+		{"other file systems",
+			mountLine("/", "/", "ext4", "rw") +
+				"22 1 8:1 / /boot rw - vfat\n" + // too short
+				"garbage\n\n",
+			nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseCgroupMounts(tt.mountinfo); !slices.Equal(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuota(t *testing.T) {
+	tests := []struct {
+		q, period string
+		want      float64
+		ok        bool
+	}{
+		{"50000", "100000", 0.5, true},
+		{"250000", "100000", 2.5, true},
+		{"-1", "100000", 0, false},
+		{"max", "100000", 0, false},
+		{"0", "100000", 0, false},
+		{"50000", "0", 0, false},
+		{"50000", "", 0, false},
+	}
+	for _, tt := range tests {
+		if got, ok := quota(tt.q, tt.period); got != tt.want || ok != tt.ok {
+			t.Errorf("quota(%q, %q) = %v, %v; want %v, %v", tt.q, tt.period, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// writeFiles creates the files, given by path relative to dir, with their
+// content.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadCPULimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		v2    bool
+		files map[string]string
+		want  float64
+		ok    bool
+	}{
+		{"v2", true, map[string]string{"cpu.max": "250000 100000\n"}, 2.5, true},
+		{"v2 max", true, map[string]string{"cpu.max": "max 100000\n"}, 0, false},
+		{"v2 malformed", true, map[string]string{"cpu.max": "250000\n"}, 0, false},
+		{"v2 missing", true, nil, 0, false},
+		{"v1", false, map[string]string{"cpu.cfs_quota_us": "150000\n", "cpu.cfs_period_us": "100000\n"}, 1.5, true},
+		{"v1 unlimited", false, map[string]string{"cpu.cfs_quota_us": "-1\n", "cpu.cfs_period_us": "100000\n"}, 0, false},
+		{"v1 missing period", false, map[string]string{"cpu.cfs_quota_us": "150000\n"}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			if got, ok := readCPULimit(dir, tt.v2); got != tt.want || ok != tt.ok {
+				t.Errorf("got %v, %v; want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCPULimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		groups string
+		// mounts maps mount points, relative to the test directory, to
+		// their root, file system type and options
+		mounts [][4]string
+		files  map[string]string
+		want   float64
+		ok     bool
+	}{
+		{"v2 ancestor",
+			"0::/user.slice/app\n",
+			[][4]string{{"/", "cg", "cgroup2", "rw"}},
+			map[string]string{
+				"cg/user.slice/cpu.max":     "200000 100000\n",
+				"cg/user.slice/app/cpu.max": "max 100000\n",
+			}, 2, true},
+		{"v2 tightest level",
+			"0::/a/b\n",
+			[][4]string{{"/", "cg", "cgroup2", "rw"}},
+			map[string]string{
+				"cg/a/cpu.max":   "400000 100000\n",
+				"cg/a/b/cpu.max": "150000 100000\n",
+			}, 1.5, true},
+		{"v2 max",
+			"0::/app\n",
+			[][4]string{{"/", "cg", "cgroup2", "rw"}},
+			map[string]string{"cg/app/cpu.max": "max 100000\n"}, 0, false},
+		{"v1",
+			"5:memory:/docker/abc\n4:cpu,cpuacct:/docker/abc\n",
+			[][4]string{{"/", "cpu", "cgroup", "rw,cpu,cpuacct"}, {"/", "memory", "cgroup", "rw,memory"}},
+			map[string]string{
+				"cpu/docker/abc/cpu.cfs_quota_us":  "150000\n",
+				"cpu/docker/abc/cpu.cfs_period_us": "100000\n",
+			}, 1.5, true},
+		{"hybrid",
+			"4:cpu,cpuacct:/app\n0::/app\n",
+			[][4]string{{"/", "cpu", "cgroup", "rw,cpu,cpuacct"}, {"/", "unified", "cgroup2", "rw"}},
+			map[string]string{
+				"cpu/app/cpu.cfs_quota_us":  "300000\n",
+				"cpu/app/cpu.cfs_period_us": "100000\n",
+				"unified/app/cpu.max":       "100000 100000\n",
+			}, 1, true},
+		{"namespaced root",
+			"0::/docker/abc/sub\n",
+			[][4]string{{"/docker/abc", "cg", "cgroup2", "rw"}},
+			map[string]string{"cg/sub/cpu.max": "50000 100000\n"}, 0.5, true},
+		{"outside namespaced root",
+			"0::/docker/abcdef\n",
+			[][4]string{{"/docker/abc", "cg", "cgroup2", "rw"}},
+			map[string]string{"cg/def/cpu.max": "50000 100000\n"}, 0, false},
+		{"no cgroup mount",
+			"0::/\n",
+			nil,
+			nil, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			var mountinfo string
+			for _, m := range tt.mounts {
+				mountinfo += mountLine(m[0], filepath.Join(dir, m[1]), m[2], m[3])
+			}
+			if got, ok := cpuLimit(tt.groups, mountinfo); got != tt.want || ok != tt.ok {
+				t.Errorf("got %v, %v; want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CheckpointFormat selects the encoding of a checkpoint file.
+type CheckpointFormat int
+
+const (
+	// CheckpointGob encodes checkpoints with encoding/gob. This is the
+	// default.
+	CheckpointGob CheckpointFormat = iota
+	// CheckpointJSON encodes checkpoints with encoding/json, which is
+	// larger but human-readable.
+	CheckpointJSON
+)
+
+// Checkpoint describes how a run persists its progress so that it can be
+// resumed after the process is killed.
+//
+// While a run is in progress, the indices of the inputs that completed
+// successfully are saved to Path together with their results every
+// Interval, and once more when the run ends, whether it finished or was
+// cancelled. The file is replaced atomically, so a crash while saving
+// leaves the previous checkpoint intact. Failed tasks are not recorded and
+// run again on resume.
+//
+// The result type must be encodable in the chosen format; with gob, results
+// holding interface values need their concrete types registered.
+type Checkpoint struct {
+	// Path of the checkpoint file. Empty disables checkpointing.
+	Path string
+	// Interval between saves. The default is one minute.
+	Interval time.Duration
+	Format   CheckpointFormat
+	// Resume restores the results saved in Path, if it exists, and runs
+	// only the remaining inputs. The inputs must be the same, in the same
+	// order, as in the run that wrote the checkpoint.
+	Resume bool
+}
+
+// WithCheckpoint persists the progress of Run, RunE and RunPartial according
+// to cp.
+func WithCheckpoint(cp Checkpoint) PoolOptionFunc {
+	return func(opts *PoolOptions) {
+		opts.Checkpoint = cp
+	}
+}
+
+// checkpointFile is the content of a checkpoint file. Results[i] is the
+// result of input Indices[i].
+type checkpointFile[R any] struct {
+	Inputs  int
+	Indices []int
+	Results []R
+}
+
+// checkpointer records the completed tasks of a run and periodically saves
+// them. A nil *checkpointer is valid and ignores all events.
+type checkpointer[R any] struct {
+	cp      Checkpoint
+	log     Logger
+	outputs []R
+
+	mu      sync.Mutex
+	indices []int
+	saved   int // len(indices) at the last save, or -1
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// startCheckpoint starts checkpointing a run collecting its results in
+// outputs. If resuming, it restores the saved results into outputs and
+// returns the bitmap of the restored inputs. It returns a nil checkpointer if
+// checkpointing is off.
+func startCheckpoint[R any](o PoolOptions, outputs []R) (*checkpointer[R], Bitmap, error) {
+	cp := o.Checkpoint
+	if cp.Path == "" {
+		return nil, nil, nil
+	}
+	if cp.Interval <= 0 {
+		cp.Interval = time.Minute
+	}
+	c := &checkpointer[R]{
+		cp:      cp,
+		log:     o.logger(),
+		outputs: outputs,
+		saved:   -1,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	var restored Bitmap
+	if cp.Resume {
+		var err error
+		if restored, err = c.restore(); err != nil {
+			return nil, nil, err
+		}
+	}
+	go c.loop()
+	return c, restored, nil
+}
+
+// restore loads the checkpoint file, if there is one, into the outputs.
+func (c *checkpointer[R]) restore() (Bitmap, error) {
+	b, err := os.ReadFile(c.cp.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("workerpool: reading checkpoint: %w", err)
+	}
+	var f checkpointFile[R]
+	if err := c.cp.Format.decode(b, &f); err != nil {
+		return nil, fmt.Errorf("workerpool: decoding checkpoint %s: %w", c.cp.Path, err)
+	}
+	if f.Inputs != len(c.outputs) || len(f.Indices) != len(f.Results) {
+		return nil, fmt.Errorf("workerpool: checkpoint %s was written for %d inputs, not %d",
+			c.cp.Path, f.Inputs, len(c.outputs))
+	}
+	restored := NewBitmap(len(c.outputs))
+	for i, idx := range f.Indices {
+		if idx < 0 || idx >= len(c.outputs) {
+			return nil, fmt.Errorf("workerpool: checkpoint %s holds invalid index %d", c.cp.Path, idx)
+		}
+		c.outputs[idx] = f.Results[i]
+		restored.Set(idx)
+	}
+	// Restored results are saved again along with the new ones
+	c.indices = f.Indices
+	c.saved = len(c.indices)
+	c.log.Info("resuming from checkpoint", "path", c.cp.Path, "completed", len(f.Indices), "inputs", f.Inputs)
+	return restored, nil
+}
+
+func (c *checkpointer[R]) loop() {
+	defer close(c.stopped)
+	ticker := time.NewTicker(c.cp.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.save()
+		}
+	}
+}
+
+// finish is the dispatch callback that records each task completed
+// successfully. The result must already be stored in the outputs.
+func (c *checkpointer[R]) finish(_, idx, _ int, err error) {
+	if c == nil || err != nil {
+		return
+	}
+	c.mu.Lock()
+	c.indices = append(c.indices, idx)
+	c.mu.Unlock()
+}
+
+// close stops the periodic saves and writes the final checkpoint.
+func (c *checkpointer[R]) close() {
+	if c == nil {
+		return
+	}
+	close(c.stop)
+	<-c.stopped
+	c.save()
+}
+
+// save writes the checkpoint file if tasks have completed since the last
+// save. Failures are logged, since the run itself can still succeed.
+func (c *checkpointer[R]) save() {
+	c.mu.Lock()
+	if len(c.indices) == c.saved {
+		c.mu.Unlock()
+		return
+	}
+	f := checkpointFile[R]{
+		Inputs:  len(c.outputs),
+		Indices: append([]int(nil), c.indices...),
+		Results: make([]R, len(c.indices)),
+	}
+	for i, idx := range f.Indices {
+		f.Results[i] = c.outputs[idx]
+	}
+	c.saved = len(c.indices)
+	c.mu.Unlock()
+
+	start := time.Now()
+	if err := c.write(&f); err != nil {
+		c.log.Warn("cannot save checkpoint", "path", c.cp.Path, "err", err)
+		return
+	}
+	c.log.Debug("checkpoint saved", "path", c.cp.Path, "completed", len(f.Indices),
+		"elapsed", time.Since(start))
+}
+
+// write replaces the checkpoint file with f through a temporary file in the
+// same directory.
+func (c *checkpointer[R]) write(f *checkpointFile[R]) error {
+	dir, base := filepath.Split(c.cp.Path)
+	if dir == "" {
+		// Not the system temporary directory, which may be on another
+		// file system
+		dir = "."
+	}
+	tmp, err := os.CreateTemp(dir, base+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := c.cp.Format.encode(tmp, f); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.cp.Path)
+}
+
+func (f CheckpointFormat) encode(w io.Writer, v any) error {
+	if f == CheckpointJSON {
+		return json.NewEncoder(w).Encode(v)
+	}
+	return gob.NewEncoder(w).Encode(v)
+}
+
+func (f CheckpointFormat) decode(b []byte, v any) error {
+	if f == CheckpointJSON {
+		return json.Unmarshal(b, v)
+	}
+	return gob.NewDecoder(bytes.NewReader(b)).Decode(v)
+}
+
+// skipping returns o set up to dispatch only those of the n inputs that are
+// not in skip.
+func (o PoolOptions) skipping(n int, skip Bitmap) PoolOptions {
+	if skip == nil {
+		return o
+	}
+	order := make([]int, 0, n-skip.Count())
+	for pos := 0; pos < n; pos++ {
+		idx := pos
+		if o.order != nil {
+			idx = o.order[pos]
+		}
+		if !skip.Has(idx) {
+			order = append(order, idx)
+		}
+	}
+	o.order = order
+	return o
+}
@@ -0,0 +1,267 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type sample struct {
+	Name   string
+	Values []float64
+}
+
+func readCheckpoint[R any](t *testing.T, cp Checkpoint) checkpointFile[R] {
+	t.Helper()
+	b, err := os.ReadFile(cp.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f checkpointFile[R]
+	if err := cp.Format.decode(b, &f); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func writeCheckpoint[R any](t *testing.T, cp Checkpoint, f checkpointFile[R]) {
+	t.Helper()
+	file, err := os.Create(cp.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := cp.Format.encode(file, &f); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// mustNotRun is a task function for runs that should restore every result.
+func mustNotRun[T, R any](t *testing.T) func(context.Context, T) (R, error) {
+	return func(context.Context, T) (R, error) {
+		t.Error("task ran although its result was restored")
+		var zero R
+		return zero, nil
+	}
+}
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	for name, format := range map[string]CheckpointFormat{"gob": CheckpointGob, "json": CheckpointJSON} {
+		t.Run(name, func(t *testing.T) {
+			cp := Checkpoint{Path: filepath.Join(t.TempDir(), "run.ckpt"), Format: format, Resume: true}
+			inputs := inputsUpTo(20)
+			want, err := New[int, sample](WithWorkers(4), WithCheckpoint(cp)).RunE(context.Background(), inputs,
+				func(_ context.Context, x int) (sample, error) {
+					return sample{Name: strings.Repeat("x", x), Values: []float64{float64(x), 0.5}}, nil
+				})
+			if err != nil {
+				t.Fatal(err)
+			}
+			f := readCheckpoint[sample](t, cp)
+			if f.Inputs != 20 || len(f.Indices) != 20 {
+				t.Fatalf("checkpoint holds %d of %d inputs, want 20 of 20", len(f.Indices), f.Inputs)
+			}
+			got, err := New[int, sample](WithWorkers(4), WithCheckpoint(cp)).RunE(context.Background(), inputs, mustNotRun[int, sample](t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("restored %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// interrupted runs the inputs and cancels the run once stop tasks have
+// succeeded. It returns the inputs whose tasks ran to completion, in the
+// order they started.
+func interrupted(t *testing.T, n, stop int, opts ...PoolOptionFunc) []int {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var (
+		mu  sync.Mutex
+		ran []int
+	)
+	_, err := New[int, int](opts...).RunE(ctx, inputsUpTo(n), func(ctx context.Context, x int) (int, error) {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		mu.Lock()
+		ran = append(ran, x)
+		if len(ran) == stop {
+			cancel()
+		}
+		mu.Unlock()
+		return x * x, nil
+	})
+	if err == nil {
+		t.Fatal("the interrupted run finished")
+	}
+	return ran
+}
+
+// resumed runs the inputs to completion and returns their results and the
+// inputs that ran, in the order they started.
+func resumed(t *testing.T, n int, opts ...PoolOptionFunc) ([]int, []int) {
+	t.Helper()
+	var (
+		mu  sync.Mutex
+		ran []int
+	)
+	out, err := New[int, int](opts...).RunE(context.Background(), inputsUpTo(n), func(_ context.Context, x int) (int, error) {
+		mu.Lock()
+		ran = append(ran, x)
+		mu.Unlock()
+		return x * x, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range out {
+		if v != i*i {
+			t.Errorf("out[%d] = %d after resuming", i, v)
+		}
+	}
+	return out, ran
+}
+
+func TestCheckpointResume(t *testing.T) {
+	cp := Checkpoint{Path: filepath.Join(t.TempDir(), "run.ckpt"), Interval: time.Millisecond, Resume: true}
+	first := interrupted(t, 100, 30, WithWorkers(4), WithCheckpoint(cp))
+	if f := readCheckpoint[int](t, cp); len(f.Indices) != len(first) {
+		t.Fatalf("checkpoint holds %d results, %d tasks succeeded", len(f.Indices), len(first))
+	}
+	_, second := resumed(t, 100, WithWorkers(4), WithCheckpoint(cp))
+	all := append(slices.Clone(first), second...)
+	slices.Sort(all)
+	if !slices.Equal(all, inputsUpTo(100)) {
+		t.Errorf("the runs together ran %v, want every input exactly once", all)
+	}
+	if f := readCheckpoint[int](t, cp); len(f.Indices) != 100 {
+		t.Errorf("final checkpoint holds %d results, want 100", len(f.Indices))
+	}
+}
+
+func TestCheckpointResumeWithCost(t *testing.T) {
+	cp := Checkpoint{Path: filepath.Join(t.TempDir(), "run.ckpt"), Resume: true}
+	cost := WithCost(func(x int) float64 { return float64(x % 5) })
+	first := interrupted(t, 20, 8, WithWorkers(1), WithCheckpoint(cp), cost)
+	_, second := resumed(t, 20, WithWorkers(1), WithCheckpoint(cp), cost)
+
+	// Without a checkpoint, a single worker runs the inputs in cost order
+	_, order := resumed(t, 20, WithWorkers(1), cost)
+	if !slices.Equal(first, order[:8]) || !slices.Equal(second, order[8:]) {
+		t.Errorf("ran %v, then %v; want %v, then %v", first, second, order[:8], order[8:])
+	}
+}
+
+func TestCheckpointHedged(t *testing.T) {
+	cp := Checkpoint{Path: filepath.Join(t.TempDir(), "run.ckpt"), Resume: true}
+	_, copies, err := hedged(t, Hedge{After: 0.5}, func(ctx context.Context, n int) (int, error) {
+		if n == 1 {
+			<-ctx.Done()
+			return -1, ctx.Err()
+		}
+		return 42, nil
+	}, WithCheckpoint(cp))
+	if err != nil || copies != 2 {
+		t.Fatalf("copies = %d, err = %v", copies, err)
+	}
+	// The task that ran twice is recorded once, with the winning result
+	f := readCheckpoint[int](t, cp)
+	indices := slices.Sorted(slices.Values(f.Indices))
+	if !slices.Equal(indices, inputsUpTo(10)) {
+		t.Errorf("checkpoint indices %v, want each input once", f.Indices)
+	}
+	if i := slices.Index(f.Indices, straggler); f.Results[i] != 42 {
+		t.Errorf("checkpointed result %d for the straggler, want 42", f.Results[i])
+	}
+	out, _, err := hedged(t, Hedge{After: 0.5}, func(context.Context, int) (int, error) {
+		t.Error("straggler ran again after resuming")
+		return 0, nil
+	}, WithCheckpoint(cp))
+	if err != nil || out[straggler] != 42 {
+		t.Errorf("resumed result %d, err = %v", out[straggler], err)
+	}
+}
+
+func TestCheckpointInputMismatch(t *testing.T) {
+	cp := Checkpoint{Path: filepath.Join(t.TempDir(), "run.ckpt"), Resume: true}
+	writeCheckpoint(t, cp, checkpointFile[int]{Inputs: 10, Indices: []int{0, 1}, Results: []int{0, 1}})
+	_, err := New[int, int](WithWorkers(2), WithCheckpoint(cp)).RunE(context.Background(), inputsUpTo(5), mustNotRun[int, int](t))
+	if err == nil || !strings.Contains(err.Error(), "written for 10 inputs, not 5") {
+		t.Errorf("err = %v, want an input count mismatch", err)
+	}
+}
+
+func TestCheckpointInvalidIndex(t *testing.T) {
+	cp := Checkpoint{Path: filepath.Join(t.TempDir(), "run.ckpt"), Resume: true}
+	writeCheckpoint(t, cp, checkpointFile[int]{Inputs: 10, Indices: []int{0, 99}, Results: []int{0, 1}})
+	_, err := New[int, int](WithWorkers(2), WithCheckpoint(cp)).RunE(context.Background(), inputsUpTo(10), mustNotRun[int, int](t))
+	if err == nil || !strings.Contains(err.Error(), "invalid index 99") {
+		t.Errorf("err = %v, want an invalid index error", err)
+	}
+}
+
+func TestCheckpointAtomicReplace(t *testing.T) {
+	dir := t.TempDir()
+	cp := Checkpoint{Path: filepath.Join(dir, "run.ckpt"), Format: CheckpointJSON, Resume: true}
+	inputs := inputsUpTo(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	New[int, float64](WithWorkers(1), WithCheckpoint(cp)).RunE(ctx, inputs, func(ctx context.Context, x int) (float64, error) {
+		if x == 5 {
+			cancel()
+			return 0, ctx.Err()
+		}
+		return float64(x), nil
+	})
+	before, err := os.ReadFile(cp.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// JSON cannot encode NaN, so saving the resumed run fails
+	_, err = New[int, float64](WithWorkers(1), WithCheckpoint(cp)).RunE(context.Background(), inputs,
+		func(context.Context, int) (float64, error) {
+			return math.NaN(), nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := os.ReadFile(cp.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Errorf("failed save changed the checkpoint from %s to %s", before, after)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want only the checkpoint", len(entries))
+	}
+}
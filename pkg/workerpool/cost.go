@@ -0,0 +1,70 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// WithCost dispatches the inputs of a run in order of decreasing cost
+// instead of slice order. With cost estimating the running time of a task,
+// this is the longest-processing-time-first rule, which keeps large tasks
+// from being left for the end of the run where they would create a long
+// tail; cost may equally be an explicit priority. Inputs of equal cost keep
+// their relative order.
+//
+// T must be the input type of the run. The cost is evaluated once per input
+// when the run starts. It applies to Run, RunE, RunPartial, RunResults,
+// RunInto, RunUntil and MapReduce, and works best with a Dynamic or Guided
+// schedule: Static hands each worker a contiguous block of the sorted order.
+// Results are still returned by input index. A run whose inputs are not
+// assignable to T fails without running any task.
+func WithCost[T any](cost func(t T) float64) PoolOptionFunc {
+	return func(opts *PoolOptions) {
+		opts.Cost = func(t any) float64 {
+			return cost(t.(T))
+		}
+		opts.costType = reflect.TypeFor[T]()
+	}
+}
+
+// planned returns o set up to dispatch inputs by decreasing cost if a cost
+// function is configured. It fails if o is invalid or the cost function set
+// by WithCost takes another type than the inputs, so callers must plan
+// before allocating per-worker state.
+func planned[T any](o PoolOptions, inputs []T) (PoolOptions, error) {
+	if err := o.validate(); err != nil {
+		return o, err
+	}
+	if o.Cost == nil {
+		return o, nil
+	}
+	if t := reflect.TypeFor[T](); o.costType != nil && !t.AssignableTo(o.costType) {
+		return o, fmt.Errorf("workerpool: cost function takes %v, not the input type %v", o.costType, t)
+	}
+	costs := make([]float64, len(inputs))
+	o.order = make([]int, len(inputs))
+	for i, t := range inputs {
+		costs[i] = o.Cost(t)
+		o.order[i] = i
+	}
+	slices.SortStableFunc(o.order, func(a, b int) int {
+		return cmp.Compare(costs[b], costs[a])
+	})
+	return o, nil
+}
@@ -0,0 +1,57 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestWithCostOrder(t *testing.T) {
+	var order []int
+	w := New[int, int](WithWorkers(1), WithCost(func(x int) float64 { return float64(x % 3) }))
+	out, err := w.Run(context.Background(), []int{0, 1, 2, 3, 4, 5}, func(_ context.Context, x int) int {
+		order = append(order, x)
+		return -x
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{2, 5, 1, 4, 0, 3}; !slices.Equal(order, want) {
+		t.Errorf("dispatch order = %v, want %v", order, want)
+	}
+	if want := []int{0, -1, -2, -3, -4, -5}; !slices.Equal(out, want) {
+		t.Errorf("results = %v, want %v", out, want)
+	}
+}
+
+func TestWithCostWrongType(t *testing.T) {
+	w := New[string, int](WithCost(func(x int) float64 { return float64(x) }))
+	ran := false
+	_, err := w.Run(context.Background(), []string{"a", "b"}, func(context.Context, string) int {
+		ran = true
+		return 0
+	})
+	if err == nil || ran {
+		t.Errorf("err = %v, ran = %v; want an error before any task runs", err, ran)
+	}
+	_, err = MapReduce(context.Background(), []string{"a"}, func(context.Context, string) int {
+		return 0
+	}, sum, WithCost(func(x int) float64 { return 0 }))
+	if err == nil {
+		t.Error("MapReduce accepted a cost function of the wrong type")
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"math"
+	"runtime"
+	"sync"
+)
+
+// DefaultWorkers returns the number of workers pools use unless configured
+// otherwise: the number of CPUs, capped on Linux by the CPU quota of the
+// process's cgroup (v1 or v2), rounded up. Inside a container limited to 2.5
+// CPUs on a 64-core host it returns 3 rather than 64, which avoids the
+// oversubscription and throttling that runtime.NumCPU would cause.
+//
+// The quota is read once per process.
+func DefaultWorkers() int {
+	return defaultWorkers()
+}
+
+var defaultWorkers = sync.OnceValue(func() int {
+	n := runtime.NumCPU()
+	if limit, ok := cgroupCPULimit(); ok {
+		n = min(n, max(1, int(math.Ceil(limit))))
+	}
+	return n
+})
@@ -0,0 +1,249 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// dispatch runs task for every index in [0, n) on NumWorkers goroutines,
+// handing out indices according to the Schedule. If o.order is set, only the
+// indices it lists are run, in that order. task receives the ID of the
+// worker running it, in [0, NumWorkers), so that callers can keep per-worker
+// state without locking.
+//
+// Each task is run through invoke, so it is subject to the task timeout and
+// retry policy. If finish is not nil, it is called from the worker with the
+// final outcome of every task that ran, outside of the task's timeout and
+// after its metrics have been recorded, so it may block.
+//
+// If o.hedge is set, a task may run on two workers at once. finish is then
+// only called for the copy that decides the outcome, from the worker that
+// ran it, so callers must keep the value of each copy per worker until then.
+//
+// dispatch returns the bitmap of indices whose task succeeded and the error
+// the run as a whole should report according to the ErrorPolicy. It returns
+// only after every worker has exited.
+func (o PoolOptions) dispatch(ctx context.Context, n int, task func(ctx context.Context, worker, idx int) error, finish func(worker, idx, attempts int, err error)) (done Bitmap, err error) {
+	if err := o.validate(); err != nil {
+		return NewBitmap(n), err
+	}
+	tasks := n
+	if o.order != nil {
+		tasks = len(o.order)
+	}
+	ctx, span := o.startRun(ctx, tasks)
+	o.logger().Debug("run started", "tasks", tasks, "workers", o.NumWorkers)
+	start := time.Now()
+	defer func() {
+		o.logEnd("run", start, err)
+		span.End(err)
+	}()
+	parent := ctx
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	sched := o.Schedule.start(tasks, o.NumWorkers)
+	ob := o.observe(tasks)
+	defer ob.close()
+	ob.enqueue(tasks)
+	queued := o.queued()
+	done = NewBitmap(n)
+	var finished atomic.Int64
+
+	var (
+		mu     sync.Mutex
+		errs   []error
+		failed error
+	)
+	// fail records a task failure and reports whether it counts as finished.
+	fail := func(idx int, err error) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		// Errors caused by the caller cancelling are reported as ctx.Err()
+		if parent.Err() != nil {
+			return false
+		}
+		err = wrapTaskError(idx, err)
+		if o.ErrorPolicy == CollectAll {
+			if errs == nil {
+				errs = make([]error, n)
+			}
+			errs[idx] = err
+			return true
+		}
+		if failed == nil {
+			failed = err
+			cancel(err)
+		}
+		return true
+	}
+
+	hg := o.hedging(tasks)
+	// record reports the outcome of a copy of task idx that worker started
+	// at start, if that copy decides the task. Otherwise only the time the
+	// worker spent on the copy is reported.
+	record := func(worker, idx int, start time.Time, attempts int, err error) {
+		if !hg.end(idx, err) {
+			ob.copyDropped(worker, start)
+			return
+		}
+		ob.taskFinished(worker, start, err)
+		if finish != nil {
+			finish(worker, idx, attempts, err)
+		}
+		if err == nil {
+			done.setAtomic(idx)
+			finished.Add(1)
+		} else if fail(idx, err) {
+			finished.Add(1)
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(o.NumWorkers)
+	for i := 0; i < o.NumWorkers; i++ {
+		go func(worker int) {
+			defer wg.Done()
+			ctx, wk := o.startWorker(ctx, worker)
+			defer o.stopWorker(wk)
+			ob.workerStarted(worker)
+			defer ob.workerStopped(worker)
+			run := func(ctx context.Context, idx int) (int, error) {
+				info := TaskInfo{Index: idx, Worker: worker, Queued: queued}
+				return o.invoke(ctx, wk, info, func(ctx context.Context) error {
+					return task(ctx, worker, idx)
+				})
+			}
+			for ctx.Err() == nil {
+				ob.await(ctx, worker)
+				lo, hi, ok := sched.take(worker)
+				if !ok {
+					// Let parked workers find out too
+					ob.exhausted()
+					break
+				}
+				for pos := lo; pos < hi && ctx.Err() == nil; pos++ {
+					idx := pos
+					if o.order != nil {
+						idx = o.order[pos]
+					}
+					start := ob.taskStarted(worker)
+					attempts, err := run(hg.begin(ctx, idx), idx)
+					record(worker, idx, start, attempts, err)
+				}
+			}
+			// Out of work, duplicate the stragglers when hedging
+			for {
+				idx, ctx, ok := hg.next(ctx)
+				if !ok {
+					return
+				}
+				start := ob.copyStarted(worker)
+				attempts, err := run(ctx, idx)
+				record(worker, idx, start, attempts, err)
+			}
+		}(i)
+	}
+	// Wait for every worker so no task outlives the call
+	wg.Wait()
+
+	switch {
+	case failed != nil:
+		return done, failed
+	case finished.Load() < int64(tasks):
+		// Context was cancelled before every task finished
+		return done, parent.Err()
+	case errs != nil:
+		return done, &TaskErrors{Errs: errs}
+	}
+	return done, nil
+}
+
+// protect runs fn on behalf of input idx, converting a panic into a
+// *TaskPanicError.
+func protect(idx int, fn func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &TaskPanicError{Index: idx, Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return fn()
+}
+
+// invoke runs fn on behalf of the task described by info, applying the task
+// timeout and the retry policy, and returns the number of attempts made. wk
+// is the worker running the task, or nil if the task context does not carry
+// one.
+func (o PoolOptions) invoke(ctx context.Context, wk *worker, info TaskInfo, fn func(ctx context.Context) error) (int, error) {
+	for attempt := 1; ; attempt++ {
+		if wk != nil {
+			wk.begin(info.Index, attempt)
+		}
+		info.Attempt = attempt
+		err := o.attempt(ctx, info, fn)
+		if err == nil || !o.Retry.retryable(ctx, attempt, err) {
+			o.logPanic(info, err)
+			return attempt, err
+		}
+		backoff := o.Retry.backoff(attempt)
+		o.logger().Debug("retrying task", "index", info.Index, "worker", info.Worker,
+			"attempt", attempt, "backoff", backoff, "err", err)
+		info.Queued = o.queued()
+		if !sleep(ctx, backoff) {
+			return attempt, err
+		}
+	}
+}
+
+// attempt makes a single attempt at running fn, bounded by the task timeout.
+func (o PoolOptions) attempt(ctx context.Context, info TaskInfo, fn func(ctx context.Context) error) (err error) {
+	if o.Tracer != nil {
+		var span Span
+		ctx, span = o.Tracer.StartTask(ctx, info)
+		defer func() { span.End(err) }()
+	}
+	if o.TaskTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.TaskTimeout)
+		defer cancel()
+	}
+	return protect(info.Index, func() error {
+		return fn(ctx)
+	})
+}
+
+// call is invoke for task functions that produce a value.
+func call[T any, R any](o PoolOptions, ctx context.Context, wk *worker, info TaskInfo, input T, fn func(ctx context.Context, t T) (R, error)) (out R, attempts int, err error) {
+	attempts, err = o.invoke(ctx, wk, info, func(ctx context.Context) error {
+		var err error
+		out, err = fn(ctx, input)
+		return err
+	})
+	return out, attempts, err
+}
+
+// wrapTaskError attaches the input index to a task failure. Panics already
+// carry their index and are returned as is.
+func wrapTaskError(idx int, err error) error {
+	if _, ok := err.(*TaskPanicError); ok {
+		return err
+	}
+	return &TaskError{Index: idx, Err: err}
+}
@@ -0,0 +1,99 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrPoolClosed is returned for tasks submitted to a Pool after Close.
+var ErrPoolClosed = errors.New("workerpool: pool is closed")
+
+// ErrNoWorkers is returned by runs configured with fewer than one worker.
+var ErrNoWorkers = errors.New("workerpool: number of workers must be positive")
+
+// ErrNotFound is returned by RunUntil when every task finished without a
+// result satisfying the predicate.
+var ErrNotFound = errors.New("workerpool: no result satisfied the predicate")
+
+// ErrorPolicy controls how RunE reacts to a failing task.
+type ErrorPolicy int
+
+const (
+	// FailFast cancels the remaining tasks on the first failure and
+	// returns that failure. This is the default.
+	FailFast ErrorPolicy = iota
+	// CollectAll runs every input regardless of failures and reports
+	// all of them at the end as a *TaskErrors.
+	CollectAll
+)
+
+// TaskError reports the failure of a single task together with the
+// index of the input it was processing.
+type TaskError struct {
+	Index int
+	Err   error
+}
+
+func (e *TaskError) Error() string {
+	return fmt.Sprintf("task %d: %v", e.Index, e.Err)
+}
+
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// TaskErrors is returned under the CollectAll policy when at least one
+// task failed. Errs has one entry per input and is nil for every task
+// that succeeded.
+type TaskErrors struct {
+	Errs []error
+}
+
+func (e *TaskErrors) Error() string {
+	return errors.Join(e.Unwrap()...).Error()
+}
+
+// Unwrap returns the non-nil task errors in input order, so that
+// errors.Is and errors.As see every failure.
+func (e *TaskErrors) Unwrap() []error {
+	var errs []error
+	for _, err := range e.Errs {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// TaskPanicError reports a panic raised by a task. The worker recovers
+// the panic so the rest of the process keeps running, and the panic is
+// then handled like any other task failure according to the ErrorPolicy.
+type TaskPanicError struct {
+	Index int
+	Value any
+	Stack []byte
+}
+
+func (e *TaskPanicError) Error() string {
+	return fmt.Sprintf("task %d: panic: %v", e.Index, e.Value)
+}
+
+// Unwrap returns the panic value if it is an error, such as a runtime.Error.
+func (e *TaskPanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
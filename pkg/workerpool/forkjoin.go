@@ -0,0 +1,280 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"math/rand/v2"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ForkJoinExecutor runs recursive divide-and-conquer computations on a pool
+// of NumWorkers goroutines. Every worker owns a deque of spawned tasks: it
+// pushes and pops at the bottom, while idle workers steal from the top of
+// other workers' deques. A task waiting in Sync keeps executing queued tasks
+// instead of blocking, so arbitrarily deep recursion never deadlocks on the
+// bounded number of workers.
+//
+// Of the PoolOptions, only NumWorkers, the worker hooks, Seed, Affinity and
+// Logger apply. The context of a Scope carries the worker, so WorkerID and
+// WorkerState work as in Run. Tasks have no input index, so Rand gives every
+// task a stream of its own numbered in the order the tasks start, which is
+// not reproducible from run to run. The options that act on individual
+// tasks, such as TaskTimeout, Retry, Metrics, Tracer and Progress, and those
+// that split inputs, such as Schedule, Cost and Autoscale, are ignored.
+type ForkJoinExecutor struct {
+	PoolOptions
+}
+
+// NewForkJoin creates a ForkJoinExecutor with optional configuration.
+func NewForkJoin(opts ...PoolOptionFunc) *ForkJoinExecutor {
+	o := defaultOpts()
+	for _, fn := range opts {
+		fn(&o)
+	}
+	return &ForkJoinExecutor{PoolOptions: o}
+}
+
+// Scope is the handle through which a fork-join task spawns and joins its
+// children. A Scope must only be used by the task it was passed to.
+type Scope struct {
+	ctx     context.Context
+	worker  *fjWorker
+	pending atomic.Int64
+}
+
+// Context returns the context of the run, carrying the worker running the
+// task. Long-running tasks should observe it to stop early once the run is
+// cancelled.
+func (s *Scope) Context() context.Context {
+	return s.ctx
+}
+
+// Spawn queues fn as a child of the current task. The child may run on any
+// worker; its effects are visible to the parent after Sync.
+func (s *Scope) Spawn(fn func(s *Scope)) {
+	s.pending.Add(1)
+	s.worker.push(&fjTask{fn: fn, parent: s})
+}
+
+// Sync waits until every child spawned so far has finished. While waiting,
+// the worker executes other queued tasks.
+func (s *Scope) Sync() {
+	w := s.worker
+	for s.pending.Load() > 0 {
+		if t := w.find(); t != nil {
+			w.exec(t)
+			continue
+		}
+		w.pool.idle(func() bool { return s.pending.Load() == 0 })
+	}
+}
+
+// Run executes root and everything it spawns, returning once all of it has
+// finished. It returns ErrNoWorkers if NumWorkers is below one, ctx.Err() if
+// the run was cancelled, or a *TaskPanicError with Index -1 if a task
+// panicked; tasks spawned after a cancellation or panic are skipped.
+func (e *ForkJoinExecutor) Run(ctx context.Context, root func(s *Scope)) (err error) {
+	if err := e.validate(); err != nil {
+		return err
+	}
+	e.logger().Debug("fork-join run started", "workers", e.NumWorkers)
+	start := time.Now()
+	defer func() { e.logEnd("fork-join run", start, err) }()
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	p := &fjPool{ctx: ctx, cancel: cancel, log: e.logger()}
+	p.cond = sync.NewCond(&p.mu)
+	p.workers = make([]*fjWorker, e.NumWorkers)
+	for i := range p.workers {
+		p.workers[i] = &fjWorker{pool: p}
+	}
+
+	// The root reports completion through a scope of its own
+	top := &Scope{ctx: ctx, worker: p.workers[0]}
+	top.Spawn(root)
+
+	var wg sync.WaitGroup
+	wg.Add(len(p.workers))
+	for i, w := range p.workers {
+		go func() {
+			defer wg.Done()
+			w.ctx, w.wk = e.startWorker(ctx, i)
+			defer e.stopWorker(w.wk)
+			w.loop(func() bool { return top.pending.Load() == 0 })
+		}()
+	}
+	wg.Wait()
+
+	if err := context.Cause(ctx); err != nil {
+		if _, ok := err.(*TaskPanicError); ok {
+			return err
+		}
+		return ctx.Err()
+	}
+	return nil
+}
+
+type fjTask struct {
+	fn     func(s *Scope)
+	parent *Scope
+}
+
+type fjPool struct {
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	log     Logger
+	workers []*fjWorker
+	queued  atomic.Int64
+	started atomic.Int64 // tasks started, numbering their Rand streams
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	sleepers atomic.Int64
+}
+
+// idle parks the calling worker until new tasks are queued or done reports
+// true.
+func (p *fjPool) idle(done func() bool) {
+	p.mu.Lock()
+	p.sleepers.Add(1)
+	for p.queued.Load() == 0 && !done() {
+		p.cond.Wait()
+	}
+	p.sleepers.Add(-1)
+	p.mu.Unlock()
+}
+
+// wake rouses parked workers. Callers update queued or a pending count
+// before calling wake, and idle checks them after registering as a sleeper,
+// so a wakeup is never lost.
+func (p *fjPool) wake() {
+	if p.sleepers.Load() == 0 {
+		return
+	}
+	p.mu.Lock()
+	p.cond.Broadcast()
+	p.mu.Unlock()
+}
+
+type fjWorker struct {
+	pool *fjPool
+	// ctx and wk are set by the worker goroutine before it runs a task
+	ctx context.Context
+	wk  *worker
+
+	mu    sync.Mutex
+	deque []*fjTask
+}
+
+func (w *fjWorker) push(t *fjTask) {
+	w.mu.Lock()
+	w.deque = append(w.deque, t)
+	w.mu.Unlock()
+	w.pool.queued.Add(1)
+	w.pool.wake()
+}
+
+// pop takes the most recently pushed task from the bottom of the deque.
+func (w *fjWorker) pop() *fjTask {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n := len(w.deque)
+	if n == 0 {
+		return nil
+	}
+	t := w.deque[n-1]
+	w.deque[n-1] = nil
+	w.deque = w.deque[:n-1]
+	return t
+}
+
+// steal takes the oldest task from the top of the deque, which in
+// divide-and-conquer workloads is usually the largest one.
+func (w *fjWorker) steal() *fjTask {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.deque) == 0 {
+		return nil
+	}
+	t := w.deque[0]
+	w.deque[0] = nil
+	w.deque = w.deque[1:]
+	return t
+}
+
+// find returns a task from the worker's own deque or, failing that, one
+// stolen from another worker starting at a random victim.
+func (w *fjWorker) find() *fjTask {
+	if t := w.pop(); t != nil {
+		w.pool.queued.Add(-1)
+		return t
+	}
+	workers := w.pool.workers
+	start := rand.IntN(len(workers))
+	for i := range workers {
+		victim := workers[(start+i)%len(workers)]
+		if victim == w {
+			continue
+		}
+		if t := victim.steal(); t != nil {
+			w.pool.queued.Add(-1)
+			return t
+		}
+	}
+	return nil
+}
+
+func (w *fjWorker) loop(done func() bool) {
+	for !done() {
+		if t := w.find(); t != nil {
+			w.exec(t)
+			continue
+		}
+		w.pool.idle(done)
+	}
+	// Let the other parked workers observe completion too
+	w.pool.wake()
+}
+
+// exec runs t on this worker and signals its parent when done.
+func (w *fjWorker) exec(t *fjTask) {
+	p := w.pool
+	if p.ctx.Err() == nil {
+		s := &Scope{ctx: w.ctx, worker: w}
+		func() {
+			defer w.wk.nest(int(p.started.Add(1) - 1))()
+			defer func() {
+				if v := recover(); v != nil {
+					err := &TaskPanicError{Index: -1, Value: v, Stack: debug.Stack()}
+					p.log.Error("fork-join task panicked", "panic", v, "stack", string(err.Stack))
+					p.cancel(err)
+				}
+			}()
+			t.fn(s)
+			// Join children the task forgot to Sync so that they finish
+			// before the parent is told this task is done
+			s.Sync()
+		}()
+	}
+	if t.parent.pending.Add(-1) == 0 {
+		p.wake()
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fib computes the n-th Fibonacci number by spawning a task per call.
+func fib(s *Scope, n int, out *int) {
+	if n < 2 {
+		*out = n
+		return
+	}
+	var a, b int
+	s.Spawn(func(s *Scope) { fib(s, n-1, &a) })
+	s.Spawn(func(s *Scope) { fib(s, n-2, &b) })
+	s.Sync()
+	*out = a + b
+}
+
+func TestForkJoinSpawnSync(t *testing.T) {
+	for _, workers := range []int{1, 2, 8} {
+		var got int
+		err := NewForkJoin(WithWorkers(workers)).Run(context.Background(), func(s *Scope) {
+			fib(s, 20, &got)
+		})
+		if err != nil || got != 6765 {
+			t.Errorf("%d workers: fib(20) = %d, %v", workers, got, err)
+		}
+	}
+}
+
+func TestForkJoinDeepRecursion(t *testing.T) {
+	// A chain far deeper than the number of workers, each level waiting on
+	// the next, must not deadlock
+	const depth = 10000
+	var descend func(s *Scope, n int, out *int)
+	descend = func(s *Scope, n int, out *int) {
+		if n == 0 {
+			return
+		}
+		var child int
+		s.Spawn(func(s *Scope) { descend(s, n-1, &child) })
+		s.Sync()
+		*out = child + 1
+	}
+	var got int
+	err := NewForkJoin(WithWorkers(2)).Run(context.Background(), func(s *Scope) {
+		descend(s, depth, &got)
+	})
+	if err != nil || got != depth {
+		t.Errorf("depth = %d, %v", got, err)
+	}
+}
+
+func TestForkJoinSyncWithoutSpawn(t *testing.T) {
+	// Children that are not synced explicitly still finish within Run
+	var count atomic.Int64
+	err := NewForkJoin(WithWorkers(4)).Run(context.Background(), func(s *Scope) {
+		for i := 0; i < 100; i++ {
+			s.Spawn(func(*Scope) { count.Add(1) })
+		}
+	})
+	if err != nil || count.Load() != 100 {
+		t.Errorf("count = %d, %v", count.Load(), err)
+	}
+}
+
+func TestForkJoinPanic(t *testing.T) {
+	err := NewForkJoin(WithWorkers(4)).Run(context.Background(), func(s *Scope) {
+		for i := 0; i < 10; i++ {
+			s.Spawn(func(*Scope) {
+				if i == 5 {
+					panic("boom")
+				}
+			})
+		}
+		s.Sync()
+	})
+	var panicErr *TaskPanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "boom" || panicErr.Index != -1 {
+		t.Fatalf("err = %v, want *TaskPanicError", err)
+	}
+}
+
+func TestForkJoinCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var ran atomic.Int64
+	errc := make(chan error, 1)
+	go func() {
+		errc <- NewForkJoin(WithWorkers(2)).Run(ctx, func(s *Scope) {
+			for i := 0; i < 1000; i++ {
+				s.Spawn(func(s *Scope) {
+					ran.Add(1)
+					select {
+					case <-s.Context().Done():
+					case <-time.After(time.Millisecond):
+					}
+				})
+			}
+		})
+	}()
+	time.Sleep(5 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-errc:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("err = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancellation")
+	}
+	if n := ran.Load(); n == 1000 {
+		t.Error("tasks spawned after cancellation still ran")
+	}
+}
+
+func TestForkJoinWorkerState(t *testing.T) {
+	var inits, teardowns atomic.Int64
+	e := NewForkJoin(WithWorkers(3),
+		WithWorkerInit(func(id int) *int {
+			inits.Add(1)
+			return &id
+		}),
+		WithWorkerTeardown(func(int, *int) { teardowns.Add(1) }))
+	var bad atomic.Int64
+	err := e.Run(context.Background(), func(s *Scope) {
+		for i := 0; i < 100; i++ {
+			s.Spawn(func(s *Scope) {
+				id := WorkerState[*int](s.Context())
+				if id == nil || *id != WorkerID(s.Context()) {
+					bad.Add(1)
+				}
+			})
+		}
+	})
+	if err != nil || bad.Load() != 0 {
+		t.Errorf("%d tasks saw the wrong worker state, err %v", bad.Load(), err)
+	}
+	if inits.Load() != 3 || teardowns.Load() != 3 {
+		t.Errorf("inits = %d, teardowns = %d, want 3", inits.Load(), teardowns.Load())
+	}
+}
+
+func TestForkJoinRandResumesAfterSync(t *testing.T) {
+	// A parent's generator continues where it left off after its children
+	// ran on the same worker during Sync
+	err := NewForkJoin(WithWorkers(1), WithSeed(1)).Run(context.Background(), func(s *Scope) {
+		r := Rand(s.Context())
+		first := r.Uint64()
+		s.Spawn(func(s *Scope) { Rand(s.Context()).Uint64() })
+		s.Sync()
+		second := r.Uint64()
+
+		var want [2]uint64
+		s.Spawn(func(s *Scope) {
+			// A fresh task numbered like the root draws the same numbers
+			w := s.worker.wk
+			defer w.nest(0)()
+			r := Rand(s.Context())
+			want[0], want[1] = r.Uint64(), r.Uint64()
+		})
+		s.Sync()
+		if first != want[0] || second != want[1] {
+			t.Errorf("root drew %d, %d, want %d, %d", first, second, want[0], want[1])
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestForkJoinNoWorkers(t *testing.T) {
+	for _, workers := range []int{0, -1} {
+		err := NewForkJoin(WithWorkers(workers)).Run(context.Background(), func(*Scope) {})
+		if !errors.Is(err, ErrNoWorkers) {
+			t.Errorf("%d workers: err = %v, want ErrNoWorkers", workers, err)
+		}
+	}
+}
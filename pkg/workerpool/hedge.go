@@ -0,0 +1,194 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Hedge configures the speculative re-execution of straggling tasks.
+//
+// Once the share After of the tasks in a run has finished, workers that run
+// out of work pick the longest-running remaining task that has not been
+// duplicated yet and run it a second time. Whichever copy succeeds first
+// provides the result, and the context of the other copy is cancelled. A
+// copy that fails while the other one is still running is ignored, so a
+// task only fails once both copies have.
+//
+// Hedging suits idempotent tasks that observe their context, with a Dynamic
+// schedule: only tasks that have started can be duplicated, so inputs still
+// queued in a chunk behind a straggler wait for it.
+//
+// Metrics and progress count every task once, when it is decided, while the
+// busy and idle times of the workers include the copies that lost.
+type Hedge struct {
+	// After is the share of finished tasks, in (0, 1], from which on
+	// stragglers are duplicated. Zero disables hedging.
+	After float64
+	// MinRuntime is how long a task must have been running before it is
+	// duplicated. Zero duplicates any task still running.
+	MinRuntime time.Duration
+}
+
+// WithHedge duplicates straggling tasks of Run, RunE and RunPartial
+// according to h.
+func WithHedge(h Hedge) PoolOptionFunc {
+	return func(opts *PoolOptions) {
+		opts.Hedge = h
+	}
+}
+
+// flight is a task with at least one copy running.
+type flight struct {
+	start   time.Time
+	copies  int
+	hedged  bool
+	cancels []context.CancelFunc
+}
+
+// hedger tracks the running tasks of one run and hands out duplicates of the
+// stragglers. A nil *hedger never duplicates and lets every copy decide the
+// outcome of its task.
+type hedger struct {
+	policy    Hedge
+	threshold int
+
+	mu        sync.Mutex
+	flights   map[int]*flight
+	completed int
+	changed   chan struct{} // closed and replaced whenever a task ends
+}
+
+// hedging returns the hedger for a run of total tasks, or nil if the run
+// does not hedge.
+func (o PoolOptions) hedging(total int) *hedger {
+	if !o.hedge || o.Hedge.After <= 0 {
+		return nil
+	}
+	return &hedger{
+		policy:    o.Hedge,
+		threshold: int(math.Ceil(min(o.Hedge.After, 1) * float64(total))),
+		flights:   make(map[int]*flight),
+		changed:   make(chan struct{}),
+	}
+}
+
+// begin registers the first copy of task idx and returns the context it
+// should run with, which is cancelled once the task has been decided.
+func (h *hedger) begin(ctx context.Context, idx int) context.Context {
+	if h == nil {
+		return ctx
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	f := &flight{start: time.Now()}
+	h.flights[idx] = f
+	return f.add(ctx)
+}
+
+// add registers another copy of f and returns its context. h.mu must be
+// held.
+func (f *flight) add(ctx context.Context) context.Context {
+	ctx, cancel := context.WithCancel(ctx)
+	f.copies++
+	f.cancels = append(f.cancels, cancel)
+	return ctx
+}
+
+// end reports whether the copy of task idx that finished with err decides
+// the outcome of the task. If it does, the other copy is cancelled.
+func (h *hedger) end(idx int, err error) bool {
+	if h == nil {
+		return true
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	f := h.flights[idx]
+	if f == nil {
+		// The other copy won
+		return false
+	}
+	f.copies--
+	if err != nil && f.copies > 0 {
+		// The other copy may still succeed
+		return false
+	}
+	delete(h.flights, idx)
+	for _, cancel := range f.cancels {
+		cancel()
+	}
+	h.completed++
+	close(h.changed)
+	h.changed = make(chan struct{})
+	return true
+}
+
+// next waits until a straggler is worth duplicating, registers a copy of it
+// and returns its index and the context the copy should run with. It returns
+// false once no task is left running or ctx is done.
+func (h *hedger) next(ctx context.Context) (int, context.Context, bool) {
+	if h == nil {
+		return 0, nil, false
+	}
+	for {
+		h.mu.Lock()
+		if len(h.flights) == 0 {
+			h.mu.Unlock()
+			return 0, nil, false
+		}
+		var (
+			pick   = -1
+			oldest time.Time
+		)
+		if h.completed >= h.threshold {
+			for idx, f := range h.flights {
+				if !f.hedged && (pick < 0 || f.start.Before(oldest)) {
+					pick, oldest = idx, f.start
+				}
+			}
+		}
+		// Wait for a task to end, or for the oldest to become old enough
+		var wait time.Duration
+		if pick >= 0 {
+			if wait = h.policy.MinRuntime - time.Since(oldest); wait <= 0 {
+				f := h.flights[pick]
+				f.hedged = true
+				ctx := f.add(ctx)
+				h.mu.Unlock()
+				return pick, ctx, true
+			}
+		}
+		changed := h.changed
+		h.mu.Unlock()
+
+		t := time.NewTimer(wait)
+		if wait <= 0 {
+			// Nothing to time, only a task ending can change the picture
+			t.Stop()
+		}
+		select {
+		case <-changed:
+		case <-t.C:
+		case <-ctx.Done():
+		}
+		t.Stop()
+		if ctx.Err() != nil {
+			return 0, nil, false
+		}
+	}
+}
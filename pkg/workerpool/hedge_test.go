@@ -0,0 +1,213 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const straggler = 0
+
+// hedged runs 10 inputs on two workers with hedging from half of them on.
+// slow is called for every execution of input straggler with the 1-based
+// number of the copy; the other inputs return their index at once.
+func hedged(t *testing.T, h Hedge, slow func(ctx context.Context, n int) (int, error), opts ...PoolOptionFunc) ([]int, int, error) {
+	t.Helper()
+	inputs := make([]int, 10)
+	for i := range inputs {
+		inputs[i] = i
+	}
+	var copies atomic.Int64
+	w := New[int, int](append([]PoolOptionFunc{WithWorkers(2), WithHedge(h)}, opts...)...)
+	out, err := w.RunE(context.Background(), inputs, func(ctx context.Context, x int) (int, error) {
+		if x != straggler {
+			return x, nil
+		}
+		return slow(ctx, int(copies.Add(1)))
+	})
+	return out, int(copies.Load()), err
+}
+
+func TestHedgeDuplicatesStraggler(t *testing.T) {
+	cancelled := make(chan struct{})
+	start := time.Now()
+	out, copies, err := hedged(t, Hedge{After: 0.5}, func(ctx context.Context, n int) (int, error) {
+		if n == 1 {
+			// The first copy hangs until the duplicate wins
+			<-ctx.Done()
+			close(cancelled)
+			return -1, ctx.Err()
+		}
+		return 42, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copies != 2 {
+		t.Errorf("straggler ran %d times, want 2", copies)
+	}
+	if out[straggler] != 42 {
+		t.Errorf("result = %d, want the duplicate's 42", out[straggler])
+	}
+	for i := 1; i < len(out); i++ {
+		if out[i] != i {
+			t.Errorf("out[%d] = %d", i, out[i])
+		}
+	}
+	select {
+	case <-cancelled:
+	default:
+		t.Error("the losing copy was not cancelled")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("run took %v", d)
+	}
+}
+
+func TestHedgeKeepsFirstResult(t *testing.T) {
+	// The original finishing first wins, and the duplicate is cancelled
+	release := make(chan struct{})
+	cancelled := make(chan struct{})
+	out, _, err := hedged(t, Hedge{After: 0.5}, func(ctx context.Context, n int) (int, error) {
+		if n == 1 {
+			<-release
+			return 7, nil
+		}
+		close(release)
+		<-ctx.Done()
+		close(cancelled)
+		return -1, ctx.Err()
+	})
+	if err != nil || out[straggler] != 7 {
+		t.Fatalf("result = %d, %v; want 7", out[straggler], err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Error("the losing duplicate was not cancelled")
+	}
+}
+
+func TestHedgeIgnoresFailureWhileOtherCopyRuns(t *testing.T) {
+	duplicated := make(chan struct{})
+	failed := make(chan struct{})
+	out, copies, err := hedged(t, Hedge{After: 0.5}, func(ctx context.Context, n int) (int, error) {
+		if n == 1 {
+			// Fail only once the duplicate is running
+			<-duplicated
+			defer close(failed)
+			return 0, errors.New("flaky")
+		}
+		close(duplicated)
+		<-failed
+		return 42, nil
+	})
+	if err != nil {
+		t.Fatalf("err = %v, want the failure to be ignored", err)
+	}
+	if copies != 2 || out[straggler] != 42 {
+		t.Errorf("copies = %d, result = %d; want 2 and 42", copies, out[straggler])
+	}
+}
+
+func TestHedgeFailsWhenBothCopiesFail(t *testing.T) {
+	duplicated := make(chan struct{})
+	_, _, err := hedged(t, Hedge{After: 0.5}, func(ctx context.Context, n int) (int, error) {
+		if n == 1 {
+			<-duplicated
+			return 0, errors.New("first")
+		}
+		close(duplicated)
+		return 0, errors.New("second")
+	})
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Index != straggler {
+		t.Errorf("err = %v, want a *TaskError for input %d", err, straggler)
+	}
+}
+
+func TestHedgeMinRuntime(t *testing.T) {
+	_, copies, err := hedged(t, Hedge{After: 0.5, MinRuntime: time.Hour}, func(ctx context.Context, n int) (int, error) {
+		time.Sleep(20 * time.Millisecond)
+		return 1, nil
+	})
+	if err != nil || copies != 1 {
+		t.Errorf("copies = %d, err = %v; want no duplicate before MinRuntime", copies, err)
+	}
+}
+
+func TestHedgeOff(t *testing.T) {
+	_, copies, err := hedged(t, Hedge{}, func(ctx context.Context, n int) (int, error) {
+		time.Sleep(20 * time.Millisecond)
+		return 1, nil
+	})
+	if err != nil || copies != 1 {
+		t.Errorf("copies = %d, err = %v; want no duplicate", copies, err)
+	}
+}
+
+// taskCounter is a Metrics counting task events.
+type taskCounter struct {
+	started, finished atomic.Int64
+}
+
+func (c *taskCounter) TaskStarted(int)                        { c.started.Add(1) }
+func (c *taskCounter) TaskFinished(int, time.Duration, error) { c.finished.Add(1) }
+func (c *taskCounter) WorkerIdle(int, time.Duration)          {}
+func (c *taskCounter) QueueDepth(int)                         {}
+
+func TestHedgeObserved(t *testing.T) {
+	const work = 50 * time.Millisecond
+	var (
+		counter taskCounter
+		mu      sync.Mutex
+		last    Progress
+	)
+	_, copies, err := hedged(t, Hedge{After: 0.5}, func(ctx context.Context, n int) (int, error) {
+		if n == 1 {
+			<-ctx.Done()
+			return -1, ctx.Err()
+		}
+		time.Sleep(work)
+		return 42, nil
+	}, WithMetrics(&counter), WithProgress(func(p Progress) {
+		mu.Lock()
+		last = p
+		mu.Unlock()
+	}))
+	if err != nil || copies != 2 {
+		t.Fatalf("copies = %d, err = %v", copies, err)
+	}
+	// Each task is counted once, however many copies ran
+	if started, finished := counter.started.Load(), counter.finished.Load(); started != 10 || finished != 10 {
+		t.Errorf("TaskStarted %d times, TaskFinished %d times; want 10", started, finished)
+	}
+	if last.Completed != 10 || !last.Done {
+		t.Errorf("final progress %+v, want 10 completed", last)
+	}
+	// Both copies of the straggler kept a worker busy
+	var busy time.Duration
+	for _, b := range last.Busy {
+		busy += b
+	}
+	if busy < 2*work {
+		t.Errorf("busy time %v, want at least %v for the two copies", busy, 2*work)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Logger receives the lifecycle events of pools: runs starting and
+// finishing, retries and panics. Arguments after msg are alternating keys
+// and values, as in log/slog, and *slog.Logger satisfies the interface
+// directly. Pools log nothing unless a Logger is configured.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// NewSlogLogger returns a Logger that writes to h.
+func NewSlogLogger(h slog.Handler) Logger {
+	return slog.New(h)
+}
+
+// WithLogger sends the pool's lifecycle events to l.
+func WithLogger(l Logger) PoolOptionFunc {
+	return func(opts *PoolOptions) {
+		opts.Logger = l
+	}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any) {}
+func (nopLogger) Info(string, ...any)  {}
+func (nopLogger) Warn(string, ...any)  {}
+func (nopLogger) Error(string, ...any) {}
+
+// logger returns the configured Logger, or one that discards everything.
+func (o PoolOptions) logger() Logger {
+	if o.Logger == nil {
+		return nopLogger{}
+	}
+	return o.Logger
+}
+
+// logEnd logs the outcome of a run of the given kind that began at start.
+func (o PoolOptions) logEnd(kind string, start time.Time, err error) {
+	log := o.logger()
+	elapsed := time.Since(start)
+	errs, _ := err.(*TaskErrors)
+	switch {
+	case err == nil:
+		log.Debug(kind+" finished", "elapsed", elapsed)
+	case errs != nil:
+		log.Warn(kind+" finished with failures", "elapsed", elapsed, "failed", len(errs.Unwrap()))
+	// Compared directly: task errors may wrap these too, e.g. on timeouts
+	case err == context.Canceled, err == context.DeadlineExceeded:
+		log.Info(kind+" cancelled", "elapsed", elapsed, "err", err)
+	default:
+		log.Warn(kind+" failed", "elapsed", elapsed, "err", err)
+	}
+}
+
+// logPanic logs err if it is the panic of a task attempt.
+func (o PoolOptions) logPanic(info TaskInfo, err error) {
+	if panicErr, ok := err.(*TaskPanicError); ok {
+		o.logger().Error("task panicked", "index", info.Index, "worker", info.Worker,
+			"attempt", info.Attempt, "panic", panicErr.Value, "stack", string(panicErr.Stack))
+	}
+}
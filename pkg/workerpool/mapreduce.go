@@ -0,0 +1,84 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"sync"
+)
+
+// Reducer describes how MapReduce folds task results of type R into an
+// accumulator of type A.
+type Reducer[R any, A any] struct {
+	// New returns an empty accumulator. It is called once per worker.
+	New func() A
+	// Add folds a single result into acc and returns the updated
+	// accumulator. It may modify acc in place.
+	Add func(acc A, r R) A
+	// Combine merges two accumulators. It must be associative, and may
+	// modify and return a.
+	Combine func(a, b A) A
+}
+
+// MapReduce applies mapFn to every input on a pool of workers configured by
+// opts and reduces the results with red.
+//
+// Each worker folds its results into its own accumulator, so the map phase
+// never contends on shared state. The per-worker accumulators are then merged
+// pairwise in a parallel tree, which keeps expensive combines such as
+// big-number additions off a single goroutine.
+//
+// Errors and panics follow the configured ErrorPolicy as in RunE. Under
+// CollectAll the accumulator of the successful inputs is returned together
+// with the *TaskErrors.
+func MapReduce[T any, R any, A any](ctx context.Context, inputs []T, mapFn func(ctx context.Context, t T) R, red Reducer[R, A], opts ...PoolOptionFunc) (A, error) {
+	o := defaultOpts()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o, err := planned(o, inputs)
+	if err != nil {
+		var zero A
+		return zero, err
+	}
+
+	accs := make([]A, o.NumWorkers)
+	for i := range accs {
+		accs[i] = red.New()
+	}
+
+	_, err = o.dispatch(ctx, len(inputs), func(ctx context.Context, worker, idx int) error {
+		accs[worker] = red.Add(accs[worker], mapFn(ctx, inputs[idx]))
+		return nil
+	}, nil)
+	if _, ok := err.(*TaskErrors); err != nil && !ok {
+		var zero A
+		return zero, err
+	}
+
+	// Merge neighbours at doubling strides: 0+1, 2+3, ... then 0+2, 4+6, ...
+	for stride := 1; stride < len(accs); stride *= 2 {
+		var wg sync.WaitGroup
+		for i := 0; i+stride < len(accs); i += 2 * stride {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				accs[i] = red.Combine(accs[i], accs[i+stride])
+			}(i)
+		}
+		wg.Wait()
+	}
+	return accs[0], err
+}
@@ -0,0 +1,69 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+var sum = Reducer[int, int]{
+	New:     func() int { return 0 },
+	Add:     func(acc, r int) int { return acc + r },
+	Combine: func(a, b int) int { return a + b },
+}
+
+func TestMapReduce(t *testing.T) {
+	inputs := make([]int, 1000)
+	for i := range inputs {
+		inputs[i] = i
+	}
+	for _, workers := range []int{1, 3, 8} {
+		got, err := MapReduce(context.Background(), inputs, func(_ context.Context, x int) int {
+			return x
+		}, sum, WithWorkers(workers))
+		if err != nil || got != 999*1000/2 {
+			t.Errorf("%d workers: got %d, %v", workers, got, err)
+		}
+	}
+}
+
+func TestMapReduceNoWorkers(t *testing.T) {
+	ctx := context.Background()
+	inputs := []int{1, 2}
+	identity := func(_ context.Context, x int) int { return x }
+	identityE := func(_ context.Context, x int) (int, error) { return x, nil }
+	for _, workers := range []int{0, -1} {
+		opts := []PoolOptionFunc{WithWorkers(workers), WithHedge(Hedge{After: 0.5})}
+		w := New[int, int](opts...)
+		_, mapErr := MapReduce(ctx, inputs, identity, sum, opts...)
+		_, runErr := w.Run(ctx, inputs, identity)
+		_, runEErr := w.RunE(ctx, inputs, identityE)
+		_, partialErr := w.RunPartial(ctx, inputs, identity)
+		_, resultsErr := w.RunResults(ctx, inputs, identityE)
+		intoErr := w.RunInto(ctx, inputs, identityE, func(int, int) error { return nil })
+		_, _, untilErr := w.RunUntil(ctx, inputs, identityE, func(int) bool { return true })
+		forErr := ParallelFor(ctx, 0, 2, func(context.Context, int) {}, opts...)
+		for name, err := range map[string]error{
+			"MapReduce": mapErr, "Run": runErr, "RunE": runEErr, "RunPartial": partialErr,
+			"RunResults": resultsErr, "RunInto": intoErr, "RunUntil": untilErr, "ParallelFor": forErr,
+		} {
+			if !errors.Is(err, ErrNoWorkers) {
+				t.Errorf("%s with %d workers: err = %v, want ErrNoWorkers", name, workers, err)
+			}
+		}
+	}
+}
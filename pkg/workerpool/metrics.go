@@ -0,0 +1,171 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics receives execution events from a pool. Implementations are called
+// from the worker goroutines and must be safe for concurrent use; they
+// should also be cheap, since they run on every task. The pkg/metrics
+// package provides an implementation that exports Prometheus metrics.
+type Metrics interface {
+	// TaskStarted is called when worker begins a task.
+	TaskStarted(worker int)
+	// TaskFinished is called when worker has finished a task, including
+	// all of its attempts. err is nil if the task succeeded.
+	TaskFinished(worker int, latency time.Duration, err error)
+	// WorkerIdle reports the time worker spent waiting for work before its
+	// latest task, or before it exited.
+	WorkerIdle(worker int, idle time.Duration)
+	// QueueDepth reports the number of tasks waiting to be started.
+	QueueDepth(depth int)
+}
+
+// WithMetrics reports execution events of every run to m.
+func WithMetrics(m Metrics) PoolOptionFunc {
+	return func(opts *PoolOptions) {
+		opts.Metrics = m
+	}
+}
+
+// observer fans the task events of one run out to its progress tracker,
+// metrics and autoscaler.
+type observer struct {
+	prog    *progress
+	metrics Metrics
+	scale   *scaler
+	queued  atomic.Int64
+	// lastEnd holds, per worker, the time its previous task ended. Each
+	// slot is only touched by its own worker.
+	lastEnd []time.Time
+}
+
+// observe sets up the observer for a run of total tasks, or -1 if unknown.
+// Invalid options get an observer that ignores every event.
+func (o PoolOptions) observe(total int) *observer {
+	if o.validate() != nil {
+		return &observer{}
+	}
+	ob := &observer{prog: o.trackProgress(total), metrics: o.Metrics}
+	if ob.metrics != nil {
+		ob.lastEnd = make([]time.Time, o.NumWorkers)
+	}
+	ob.scale = o.autoscale(&ob.queued)
+	return ob
+}
+
+// await parks worker while the autoscaler keeps it inactive.
+func (ob *observer) await(ctx context.Context, worker int) {
+	ob.scale.await(ctx, worker)
+}
+
+// exhausted signals that no more tasks will be enqueued.
+func (ob *observer) exhausted() {
+	ob.scale.exhausted()
+}
+
+func (ob *observer) timed() bool {
+	return ob.prog != nil || ob.metrics != nil
+}
+
+// workerStarted marks the beginning of worker's first wait for work.
+func (ob *observer) workerStarted(worker int) {
+	if ob.metrics != nil {
+		ob.lastEnd[worker] = time.Now()
+	}
+}
+
+// workerStopped reports the idle time of worker since its last task.
+func (ob *observer) workerStopped(worker int) {
+	if ob.metrics != nil {
+		ob.metrics.WorkerIdle(worker, time.Since(ob.lastEnd[worker]))
+	}
+}
+
+// enqueue records n more tasks waiting to be started.
+func (ob *observer) enqueue(n int) {
+	depth := ob.queued.Add(int64(n))
+	if ob.metrics != nil {
+		ob.metrics.QueueDepth(int(depth))
+	}
+}
+
+// taskStarted records that worker picked up a task and returns its start
+// time, or the zero time if nothing needs timing.
+func (ob *observer) taskStarted(worker int) time.Time {
+	depth := ob.queued.Add(-1)
+	if !ob.timed() {
+		return time.Time{}
+	}
+	start := time.Now()
+	if ob.metrics != nil {
+		ob.metrics.QueueDepth(int(depth))
+		ob.metrics.WorkerIdle(worker, start.Sub(ob.lastEnd[worker]))
+		ob.metrics.TaskStarted(worker)
+	}
+	return start
+}
+
+// copyStarted records that worker began a duplicate of a running task when
+// hedging and returns its start time, or the zero time if nothing needs
+// timing. The task itself was already counted as started by its first copy.
+func (ob *observer) copyStarted(worker int) time.Time {
+	if !ob.timed() {
+		return time.Time{}
+	}
+	start := time.Now()
+	if ob.metrics != nil {
+		ob.metrics.WorkerIdle(worker, start.Sub(ob.lastEnd[worker]))
+	}
+	return start
+}
+
+// copyDropped records that worker spent the time since start on a copy of a
+// task that the other copy decided. The time counts as busy, but the task is
+// only reported as finished once, by the deciding copy.
+func (ob *observer) copyDropped(worker int, start time.Time) {
+	if !ob.timed() {
+		return
+	}
+	end := time.Now()
+	ob.prog.copyDropped(worker, end.Sub(start))
+	if ob.metrics != nil {
+		ob.lastEnd[worker] = end
+	}
+}
+
+// taskFinished records the outcome of a task that worker started at start.
+func (ob *observer) taskFinished(worker int, start time.Time, err error) {
+	ob.scale.taskDone()
+	if !ob.timed() {
+		return
+	}
+	end := time.Now()
+	ob.prog.taskDone(worker, end.Sub(start), err)
+	if ob.metrics != nil {
+		ob.metrics.TaskFinished(worker, end.Sub(start), err)
+		ob.lastEnd[worker] = end
+	}
+}
+
+// close ends the run, sending the final progress report.
+func (ob *observer) close() {
+	ob.scale.close()
+	ob.prog.close()
+}
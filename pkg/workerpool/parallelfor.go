@@ -0,0 +1,36 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import "context"
+
+// ParallelFor calls body for every i in [lo, hi) on a pool of workers
+// configured by opts. The range is split into chunks according to the
+// Schedule option, so there is no need to batch indices by hand.
+//
+// It returns ctx.Err() if the loop was cancelled before completing, and a
+// panic inside body is reported as a *TaskPanicError whose Index is the
+// offset i-lo.
+func ParallelFor(ctx context.Context, lo, hi int, body func(ctx context.Context, i int), opts ...PoolOptionFunc) error {
+	o := defaultOpts()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	_, err := o.dispatch(ctx, max(hi-lo, 0), func(ctx context.Context, _ int, idx int) error {
+		body(ctx, lo+idx)
+		return nil
+	}, nil)
+	return err
+}
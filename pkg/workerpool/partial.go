@@ -0,0 +1,72 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"math/bits"
+	"sync/atomic"
+)
+
+// Bitmap is a fixed-size set of input indices stored one bit per input.
+type Bitmap []uint64
+
+// NewBitmap returns an empty Bitmap able to hold indices [0, n).
+func NewBitmap(n int) Bitmap {
+	return make(Bitmap, (n+63)/64)
+}
+
+// Set marks index i as present.
+func (b Bitmap) Set(i int) {
+	b[i/64] |= 1 << (uint(i) % 64)
+}
+
+// setAtomic is like Set but safe to call from concurrent goroutines.
+func (b Bitmap) setAtomic(i int) {
+	atomic.OrUint64(&b[i/64], 1<<(uint(i)%64))
+}
+
+// Has reports whether index i is present.
+func (b Bitmap) Has(i int) bool {
+	return b[i/64]&(1<<(uint(i)%64)) != 0
+}
+
+// Count returns the number of indices present.
+func (b Bitmap) Count() int {
+	n := 0
+	for _, word := range b {
+		n += bits.OnesCount64(word)
+	}
+	return n
+}
+
+// Partial is the outcome of a run that may have stopped before every input
+// was processed. Results[i] holds a valid value only if Done.Has(i).
+type Partial[R any] struct {
+	Results []R
+	Done    Bitmap
+}
+
+// Completed returns the number of inputs whose result is available.
+func (p Partial[R]) Completed() int {
+	return p.Done.Count()
+}
+
+// Fraction returns the completed share of the inputs in the range [0, 1].
+func (p Partial[R]) Fraction() float64 {
+	if len(p.Results) == 0 {
+		return 1
+	}
+	return float64(p.Completed()) / float64(len(p.Results))
+}
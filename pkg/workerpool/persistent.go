@@ -0,0 +1,222 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Future is the pending result of a task submitted to a Pool.
+type Future[R any] struct {
+	done  chan struct{}
+	value R
+	err   error
+}
+
+func newFuture[R any]() *Future[R] {
+	return &Future[R]{done: make(chan struct{})}
+}
+
+func (f *Future[R]) resolve(value R, err error) {
+	f.value, f.err = value, err
+	close(f.done)
+}
+
+// Done returns a channel that is closed once the result is available.
+func (f *Future[R]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Get blocks until the task has finished or ctx is cancelled, and returns
+// the task's result. Cancelling ctx only stops the wait, not the task.
+func (f *Future[R]) Get(ctx context.Context) (R, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		var zero R
+		return zero, ctx.Err()
+	}
+}
+
+// Pool is a long-lived set of workers that executes fn on values submitted
+// over time. Unlike WorkerPoolExecutor, which starts fresh goroutines on
+// every Run, a Pool reuses the same NumWorkers goroutines from Start until
+// Close.
+type Pool[T any, R any] struct {
+	PoolOptions
+	fn func(ctx context.Context, t T) (R, error)
+
+	jobs     chan job[T, R]
+	ob       *observer
+	invalid  error // fails every submission if the options are invalid
+	start    sync.Once
+	wg       sync.WaitGroup
+	finished chan struct{}
+
+	mu        sync.RWMutex // held for reading by sends on jobs
+	closing   chan struct{}
+	closeOnce sync.Once
+	seq       atomic.Int64
+}
+
+type job[T any, R any] struct {
+	ctx    context.Context
+	idx    int
+	input  T
+	queued time.Time
+	future *Future[R]
+}
+
+// run executes the job on worker wk and resolves its future.
+func (j job[T, R]) run(o PoolOptions, ob *observer, wk *worker, fn func(ctx context.Context, t T) (R, error)) {
+	id := wk.id
+	start := ob.taskStarted(id)
+	// Skip work whose submitter has already given up
+	if err := j.ctx.Err(); err != nil {
+		var zero R
+		j.future.resolve(zero, err)
+		ob.taskFinished(id, start, err)
+		return
+	}
+	value, _, err := call(o, wk.attach(j.ctx), wk, TaskInfo{Index: j.idx, Worker: id, Queued: j.queued}, j.input, fn)
+	j.future.resolve(value, err)
+	ob.taskFinished(id, start, err)
+}
+
+// NewPool creates a Pool that runs fn on every submitted value. The pool
+// accepts submissions right away but only executes them after Start. With
+// fewer than one worker, every submission fails with ErrNoWorkers.
+func NewPool[T any, R any](fn func(ctx context.Context, t T) (R, error), opts ...PoolOptionFunc) *Pool[T, R] {
+	o := defaultOpts()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Pool[T, R]{
+		PoolOptions: o,
+		fn:          fn,
+		jobs:        make(chan job[T, R], max(o.NumWorkers, 0)),
+		ob:          o.observe(-1),
+		invalid:     o.validate(),
+		finished:    make(chan struct{}),
+		closing:     make(chan struct{}),
+	}
+}
+
+// Start launches the workers. Calling Start more than once has no effect.
+func (p *Pool[T, R]) Start() {
+	p.start.Do(func() {
+		if p.invalid != nil {
+			return
+		}
+		p.logger().Debug("pool started", "workers", p.NumWorkers)
+		p.wg.Add(p.NumWorkers)
+		for i := 0; i < p.NumWorkers; i++ {
+			go func(id int) {
+				defer p.wg.Done()
+				_, wk := p.startWorker(context.Background(), id)
+				defer p.stopWorker(wk)
+				p.ob.workerStarted(id)
+				defer p.ob.workerStopped(id)
+				for {
+					p.ob.await(context.Background(), id)
+					j, ok := <-p.jobs
+					if !ok {
+						return
+					}
+					j.run(p.PoolOptions, p.ob, wk, p.fn)
+				}
+			}(i)
+		}
+	})
+}
+
+// Submit queues t for execution and returns a Future for its result. ctx is
+// passed to fn and bounds the time Submit may block when the queue is full.
+//
+// fn's error is reported through the Future unchanged; a panic is reported as
+// a *TaskPanicError whose Index is the submission's sequence number. After
+// Close the Future fails immediately with ErrPoolClosed, and so does a
+// submission still blocked on a full queue when Close is called.
+func (p *Pool[T, R]) Submit(ctx context.Context, t T) *Future[R] {
+	f := newFuture[R]()
+	var zero R
+
+	if p.invalid != nil {
+		f.resolve(zero, p.invalid)
+		return f
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	select {
+	case <-p.closing:
+		f.resolve(zero, ErrPoolClosed)
+		return f
+	default:
+	}
+
+	p.ob.enqueue(1)
+	select {
+	case p.jobs <- job[T, R]{ctx: ctx, idx: int(p.seq.Add(1) - 1), input: t, queued: p.queued(), future: f}:
+	case <-p.closing:
+		p.ob.enqueue(-1)
+		f.resolve(zero, ErrPoolClosed)
+	case <-ctx.Done():
+		p.ob.enqueue(-1)
+		f.resolve(zero, ctx.Err())
+	}
+	return f
+}
+
+// Close stops accepting submissions. Tasks already queued still run; use
+// Wait to block until they have finished. If the pool was never started,
+// the queued tasks fail with ErrPoolClosed instead, and a later Start has
+// no effect.
+func (p *Pool[T, R]) Close() {
+	p.closeOnce.Do(p.close)
+}
+
+func (p *Pool[T, R]) close() {
+	// Release the submissions blocked on a full queue, which hold p.mu
+	close(p.closing)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	close(p.jobs)
+	p.start.Do(func() {
+		var zero R
+		for j := range p.jobs {
+			p.ob.enqueue(-1)
+			j.future.resolve(zero, ErrPoolClosed)
+		}
+	})
+	p.ob.exhausted()
+	p.logger().Debug("pool closed", "submitted", p.seq.Load())
+	go func() {
+		p.wg.Wait()
+		p.ob.close()
+		p.logger().Debug("pool stopped")
+		close(p.finished)
+	}()
+}
+
+// Wait blocks until every worker has exited, which happens once the pool
+// has been closed and its queue drained.
+func (p *Pool[T, R]) Wait() {
+	<-p.finished
+}
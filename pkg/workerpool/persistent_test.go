@@ -0,0 +1,154 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func square(_ context.Context, x int) (int, error) {
+	return x * x, nil
+}
+
+// get waits a bounded time for f, failing the test if it never resolves.
+func get[R any](t *testing.T, f *Future[R]) (R, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	v, err := f.Get(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("future was never resolved")
+	}
+	return v, err
+}
+
+func TestPoolRunsQueuedTasksAfterClose(t *testing.T) {
+	p := NewPool(square, WithWorkers(2))
+	futures := []*Future[int]{
+		p.Submit(context.Background(), 2),
+		p.Submit(context.Background(), 3),
+	}
+	p.Start()
+	p.Close()
+	p.Wait()
+	for i, f := range futures {
+		v, err := get(t, f)
+		if err != nil || v != (i+2)*(i+2) {
+			t.Errorf("future %d = %d, %v", i, v, err)
+		}
+	}
+}
+
+func TestPoolCloseBeforeStart(t *testing.T) {
+	p := NewPool(square, WithWorkers(1))
+	f := p.Submit(context.Background(), 2)
+	p.Close()
+	p.Wait()
+	if _, err := get(t, f); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("err = %v, want ErrPoolClosed", err)
+	}
+	p.Start()
+	if _, err := get(t, p.Submit(context.Background(), 3)); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("err after Close = %v, want ErrPoolClosed", err)
+	}
+}
+
+func TestPoolCloseReleasesBlockedSubmit(t *testing.T) {
+	p := NewPool(square, WithWorkers(1))
+	// Fill the queue of the unstarted pool so that the next Submit blocks
+	for i := 0; i < p.NumWorkers; i++ {
+		p.Submit(context.Background(), i)
+	}
+	blocked := make(chan *Future[int])
+	go func() {
+		blocked <- p.Submit(context.Background(), 42)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		p.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close hung on a blocked Submit")
+	}
+	if _, err := get(t, <-blocked); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("err = %v, want ErrPoolClosed", err)
+	}
+	p.Wait()
+}
+
+func TestPoolWorkerState(t *testing.T) {
+	type state struct{ id int }
+	p := NewPool(func(ctx context.Context, x int) (int, error) {
+		s := WorkerState[*state](ctx)
+		if s == nil || s.id != WorkerID(ctx) {
+			return 0, errors.New("wrong worker state")
+		}
+		return int(Rand(ctx).Uint64() % 1000), nil
+	}, WithWorkers(3), WithSeed(7), WithWorkerInit(func(id int) *state {
+		return &state{id: id}
+	}))
+	p.Start()
+	var futures []*Future[int]
+	for i := 0; i < 20; i++ {
+		futures = append(futures, p.Submit(context.Background(), i))
+	}
+	p.Close()
+	p.Wait()
+
+	// Rand is keyed by the submission's sequence number
+	q := NewPool(func(ctx context.Context, x int) (int, error) {
+		return int(Rand(ctx).Uint64() % 1000), nil
+	}, WithWorkers(1), WithSeed(7))
+	q.Start()
+	for i, f := range futures {
+		v, err := get(t, f)
+		if err != nil {
+			t.Fatalf("task %d: %v", i, err)
+		}
+		if want, _ := get(t, q.Submit(context.Background(), i)); v != want {
+			t.Errorf("task %d drew %d, want %d", i, v, want)
+		}
+	}
+	q.Close()
+	q.Wait()
+}
+
+func TestPoolNoWorkers(t *testing.T) {
+	for _, workers := range []int{0, -1} {
+		p := NewPool(square, WithWorkers(workers), WithProgress(func(Progress) {}))
+		p.Start()
+		if _, err := get(t, p.Submit(context.Background(), 2)); !errors.Is(err, ErrNoWorkers) {
+			t.Errorf("Pool with %d workers: err = %v, want ErrNoWorkers", workers, err)
+		}
+		p.Close()
+		p.Wait()
+
+		q := NewPriorityPool(square, WithWorkers(workers))
+		q.Start()
+		if _, err := get(t, q.Submit(context.Background(), 2, 0)); !errors.Is(err, ErrNoWorkers) {
+			t.Errorf("PriorityPool with %d workers: err = %v, want ErrNoWorkers", workers, err)
+		}
+		q.Close()
+		q.Wait()
+	}
+}
@@ -18,19 +18,73 @@ package workerpool
 
 import (
 	"context"
-	"runtime"
+	"math/rand/v2"
+	"reflect"
 	"sync"
+	"time"
 )
 
 type PoolOptions struct {
-	NumWorkers int
+	NumWorkers  int
+	ErrorPolicy ErrorPolicy
+	// BufferSize bounds the number of inputs a stream pulls ahead of its
+	// consumer. Zero selects twice the number of workers.
+	BufferSize int
+	// Ordered makes streams emit results in input order.
+	Ordered bool
+	// Schedule controls how Run, MapReduce and ParallelFor split their
+	// inputs among workers.
+	Schedule Schedule
+	// WorkerInit and WorkerTeardown are set by WithWorkerInit and
+	// WithWorkerTeardown.
+	WorkerInit     func(workerID int) any
+	WorkerTeardown func(workerID int, state any)
+	// Cost is set by WithCost.
+	Cost func(t any) float64
+	// costType is the input type of the function passed to WithCost.
+	costType reflect.Type
+	// Seed is the master seed of the per-task generators returned by Rand.
+	// It is random unless set with WithSeed.
+	Seed uint64
+	// TaskTimeout bounds every task attempt when positive.
+	TaskTimeout time.Duration
+	// Retry controls whether failed tasks are run again.
+	Retry RetryPolicy
+	// Progress, when set, receives a snapshot every ProgressInterval.
+	Progress         func(Progress)
+	ProgressInterval time.Duration
+	// Metrics, when set, receives task and worker events.
+	Metrics Metrics
+	// Tracer, when set, wraps runs and task attempts in spans.
+	Tracer Tracer
+	// Autoscale, when Max is set, varies the number of active workers.
+	Autoscale Autoscale
+	// Affinity pins workers to CPUs.
+	Affinity Affinity
+	// Checkpoint, when Path is set, persists the progress of runs.
+	Checkpoint Checkpoint
+	// Hedge, when After is set, duplicates straggling tasks.
+	Hedge Hedge
+	// Logger, when set, receives lifecycle events such as runs starting
+	// and finishing, retries and panics.
+	Logger Logger
+
+	// order, when set, is the permutation of input indices in which a run
+	// dispatches its tasks.
+	order []int
+	// hedge is set by the runs whose tasks may be duplicated according to
+	// Hedge.
+	hedge bool
 }
 
 type PoolOptionFunc func(*PoolOptions)
 
 func defaultOpts() PoolOptions {
 	return PoolOptions{
-		NumWorkers: runtime.NumCPU(),
+		NumWorkers:  DefaultWorkers(),
+		ErrorPolicy: FailFast,
+		Schedule:    Dynamic(1),
+		Seed:        rand.Uint64(),
 	}
 }
 
@@ -41,6 +95,75 @@ func WithWorkers(num int) PoolOptionFunc {
 	}
 }
 
+// WithErrorPolicy selects how RunE handles failing and panicking tasks.
+func WithErrorPolicy(policy ErrorPolicy) PoolOptionFunc {
+	return func(opts *PoolOptions) {
+		opts.ErrorPolicy = policy
+	}
+}
+
+// WithBuffer sets the number of inputs a stream may hold in flight.
+func WithBuffer(size int) PoolOptionFunc {
+	return func(opts *PoolOptions) {
+		opts.BufferSize = size
+	}
+}
+
+// WithOrdered makes streams emit results in input order rather than in
+// completion order.
+func WithOrdered() PoolOptionFunc {
+	return func(opts *PoolOptions) {
+		opts.Ordered = true
+	}
+}
+
+// WithSchedule selects how inputs are split into chunks for the workers.
+func WithSchedule(schedule Schedule) PoolOptionFunc {
+	return func(opts *PoolOptions) {
+		opts.Schedule = schedule
+	}
+}
+
+// WithSeed fixes the master seed of the per-task generators returned by
+// Rand, making runs reproducible.
+func WithSeed(seed uint64) PoolOptionFunc {
+	return func(opts *PoolOptions) {
+		opts.Seed = seed
+	}
+}
+
+// WithTaskTimeout gives every task attempt a context that expires after d,
+// so a single hung task cannot stall a run. Tasks must observe their context
+// for the timeout to take effect.
+func WithTaskTimeout(d time.Duration) PoolOptionFunc {
+	return func(opts *PoolOptions) {
+		opts.TaskTimeout = d
+	}
+}
+
+// WithRetry retries failed tasks according to policy. The number of attempts
+// each task took is reported in its Result.
+func WithRetry(policy RetryPolicy) PoolOptionFunc {
+	return func(opts *PoolOptions) {
+		opts.Retry = policy
+	}
+}
+
+// validate reports a configuration that no run can execute.
+func (o PoolOptions) validate() error {
+	if o.NumWorkers <= 0 {
+		return ErrNoWorkers
+	}
+	return nil
+}
+
+func (o PoolOptions) bufferSize() int {
+	if o.BufferSize > 0 {
+		return o.BufferSize
+	}
+	return 2 * o.NumWorkers
+}
+
 // WorkerPoolExecutor manages a pool of goroutines to execute tasks.
 // T is the input type, R is the output type.
 type WorkerPoolExecutor[T any, R any] struct {
@@ -61,85 +184,210 @@ func New[T any, R any](opts ...PoolOptionFunc) *WorkerPoolExecutor[T, R] {
 // Run dispatches each input through fn concurrently, using up to NumWorkers.
 // It returns a slice of results in the same order as inputs.
 func (w *WorkerPoolExecutor[T, R]) Run(ctx context.Context, inputs []T, fn func(ctx context.Context, t T) R) ([]R, error) {
-	// Internal types to carry index for ordering
-	type task struct {
+	return w.RunE(ctx, inputs, func(ctx context.Context, t T) (R, error) {
+		return fn(ctx, t), nil
+	})
+}
+
+// RunE is like Run but accepts a task function that can fail.
+//
+// Under the FailFast policy the first failure cancels the context passed to
+// the remaining tasks and RunE returns it as a *TaskError carrying the index
+// of the failing input. Under the CollectAll policy every input is run, and
+// if any failed RunE returns the results together with a *TaskErrors holding
+// one error per input.
+//
+// A panic inside fn is recovered and reported as a *TaskPanicError, which is
+// treated like a returned error: FailFast aborts the run, while CollectAll
+// keeps going with the remaining inputs.
+//
+// RunE returns only after all workers have exited, so fn should observe ctx
+// to return promptly once the run is cancelled.
+func (w *WorkerPoolExecutor[T, R]) RunE(ctx context.Context, inputs []T, fn func(ctx context.Context, t T) (R, error)) ([]R, error) {
+	outputs, _, err := w.run(ctx, inputs, fn)
+	if _, ok := err.(*TaskErrors); err != nil && !ok {
+		return nil, err
+	}
+	return outputs, err
+}
+
+// RunPartial is like Run but keeps the work that finished when the run is
+// cancelled. It always returns a Partial with one slot per input, whose Done
+// bitmap marks the results that are valid, together with the same error Run
+// would have returned.
+func (w *WorkerPoolExecutor[T, R]) RunPartial(ctx context.Context, inputs []T, fn func(ctx context.Context, t T) R) (Partial[R], error) {
+	outputs, done, err := w.run(ctx, inputs, func(ctx context.Context, t T) (R, error) {
+		return fn(ctx, t), nil
+	})
+	return Partial[R]{Results: outputs, Done: done}, err
+}
+
+// RunResults is like RunE but returns a Result for every input, carrying the
+// task's value, its error and the number of attempts it took. The results
+// are returned even when the run stops early; entries with zero Attempts
+// belong to tasks that never ran.
+func (w *WorkerPoolExecutor[T, R]) RunResults(ctx context.Context, inputs []T, fn func(ctx context.Context, t T) (R, error)) ([]Result[R], error) {
+	o, err := planned(w.PoolOptions, inputs)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]Result[R], len(inputs))
+	for i := range results {
+		results[i].Index = i
+	}
+	_, err = o.dispatch(ctx, len(inputs), func(ctx context.Context, _ int, idx int) error {
+		value, err := fn(ctx, inputs[idx])
+		results[idx].Value = value
+		return err
+	}, func(_, idx, attempts int, err error) {
+		results[idx].Attempts = attempts
+		if err != nil {
+			results[idx].Err = wrapTaskError(idx, err)
+		}
+	})
+	return results, err
+}
+
+// RunInto is like RunE but hands every successful result to sink instead of
+// collecting them. sink is called on the calling goroutine, one result at a
+// time and in completion order regardless of Ordered; idx is the position of
+// the input.
+//
+// At most BufferSize results are held between the workers and sink. Once
+// the buffer is full, workers wait for sink to catch up before starting new
+// tasks, so a slow sink throttles the run instead of piling up results.
+// The bookkeeping of the run still grows with the inputs: a bit per input
+// tracks completion, CollectAll keeps an error slot per input and WithCost
+// an index per input.
+//
+// If sink returns an error, the run is cancelled and RunInto returns that
+// error once every worker has exited. Otherwise it returns the error RunE
+// would have returned.
+func (w *WorkerPoolExecutor[T, R]) RunInto(ctx context.Context, inputs []T, fn func(ctx context.Context, t T) (R, error), sink func(idx int, r R) error) error {
+	o, err := planned(w.PoolOptions, inputs)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type item struct {
 		idx   int
-		input T
-	}
-	type result struct {
-		idx    int
-		output R
-	}
-
-	tasks := make(chan task)
-	results := make(chan result, len(inputs))
-
-	var wg sync.WaitGroup
-	wg.Add(w.NumWorkers)
-	// Start worker goroutines
-	for i := 0; i < w.NumWorkers; i++ {
-		go func() {
-			defer wg.Done()
-			for {
-				select {
-				// 1) Check for cancellation
-				case <-ctx.Done():
-					return
-				// 2) Try to pull a task
-				case t, ok := <-tasks:
-					if !ok {
-						// tasks channel closed → all done
-						return
-					}
-					// 3) Do the  work
-					out := fn(ctx, t.input)
-					// 4) Try to send result, but return on cancel
-					select {
-					case <-ctx.Done():
-						return
-					case results <- result{idx: t.idx, output: out}:
-					}
-				}
-			}
-		}()
+		value R
 	}
+	items := make(chan item, w.bufferSize())
+	// Each worker parks its latest value here until finish hands it over
+	values := make([]R, w.NumWorkers)
 
-	// Feed tasks and close channels appropriately
 	go func() {
-		for i, input := range inputs {
+		defer close(items)
+		_, err = o.dispatch(ctx, len(inputs), func(ctx context.Context, worker, idx int) error {
+			var err error
+			values[worker], err = fn(ctx, inputs[idx])
+			return err
+		}, func(worker, idx, _ int, err error) {
+			if err != nil {
+				return
+			}
 			select {
+			case items <- item{idx: idx, value: values[worker]}:
 			case <-ctx.Done():
-				return // exits the goroutine immediately
-			case tasks <- task{idx: i, input: input}:
 			}
-		}
-		close(tasks)
+			var zero R
+			values[worker] = zero
+		})
 	}()
 
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
+	for it := range items {
+		if sinkErr := sink(it.idx, it.value); sinkErr != nil {
+			cancel()
+			// Drain so that no worker stays blocked on a send
+			for range items {
+			}
+			return sinkErr
+		}
+	}
+	return err
+}
+
+// RunUntil searches inputs for a result satisfying pred. As soon as a task
+// returns such a result, the outstanding tasks are cancelled through their
+// context and RunUntil returns the index of the winning input and its result
+// once every worker has exited. pred is called from the workers, possibly
+// concurrently. If several results satisfy it, the first one reported wins.
+//
+// Task errors follow the ErrorPolicy: under FailFast the search stops at the
+// first failure, under CollectAll it goes on and the failures are only
+// returned if no winner is found. If the search completes without a winner,
+// RunUntil returns -1 and ErrNotFound.
+func (w *WorkerPoolExecutor[T, R]) RunUntil(ctx context.Context, inputs []T, fn func(ctx context.Context, t T) (R, error), pred func(r R) bool) (int, R, error) {
+	o, err := planned(w.PoolOptions, inputs)
+	if err != nil {
+		var zero R
+		return -1, zero, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
+	var (
+		once   sync.Once
+		winner = -1
+		value  R
+	)
+	_, err = o.dispatch(ctx, len(inputs), func(ctx context.Context, _ int, idx int) error {
+		r, err := fn(ctx, inputs[idx])
+		if err == nil && pred(r) {
+			once.Do(func() {
+				winner, value = idx, r
+				cancel()
+			})
+		}
+		return err
+	}, nil)
+	if winner >= 0 {
+		return winner, value, nil
+	}
+	if err == nil {
+		err = ErrNotFound
+	}
+	var zero R
+	return -1, zero, err
+}
+
+// run is the engine shared by the Run variants. It always returns the
+// outputs collected so far and the bitmap of inputs that completed
+// successfully; err is the error the run as a whole should report.
+func (w *WorkerPoolExecutor[T, R]) run(ctx context.Context, inputs []T, fn func(ctx context.Context, t T) (R, error)) ([]R, Bitmap, error) {
 	outputs := make([]R, len(inputs))
-	collected := 0
-
-	// Keep going until we've seen every expected result
-	for collected < len(inputs) {
-		select {
-		case <-ctx.Done():
-			// Context was cancelled: abort immediately, return the error
-			return nil, ctx.Err()
-
-		case r, ok := <-results:
-			if !ok {
-				// results closed unexpectedly (shouldn't really happen unless you close it early), so just return what we have
-				return outputs, nil
-			}
+	o, err := planned(w.PoolOptions, inputs)
+	if err != nil {
+		return outputs, NewBitmap(len(inputs)), err
+	}
+	cp, restored, err := startCheckpoint(o, outputs)
+	if err != nil {
+		return outputs, NewBitmap(len(inputs)), err
+	}
+	o = o.skipping(len(inputs), restored)
+	o.hedge = true
+	// Each worker parks its latest value here until the task is decided,
+	// since a duplicate of the task may be running as well
+	values := make([]R, w.NumWorkers)
+	done, err := o.dispatch(ctx, len(inputs), func(ctx context.Context, worker, idx int) error {
+		var err error
+		values[worker], err = fn(ctx, inputs[idx])
+		return err
+	}, func(worker, idx, attempts int, err error) {
+		if err == nil {
 			// Store by index so order is preserved
-			outputs[r.idx] = r.output
-			collected++
+			outputs[idx] = values[worker]
 		}
+		var zero R
+		values[worker] = zero
+		cp.finish(worker, idx, attempts, err)
+	})
+	cp.close()
+	for i := range restored {
+		done[i] |= restored[i]
 	}
-	return outputs, nil
+	return outputs, done, err
 }
@@ -0,0 +1,220 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errOdd = errors.New("odd")
+
+func inputsUpTo(n int) []int {
+	inputs := make([]int, n)
+	for i := range inputs {
+		inputs[i] = i
+	}
+	return inputs
+}
+
+func TestRun(t *testing.T) {
+	out, err := New[int, int](WithWorkers(4)).Run(context.Background(), inputsUpTo(100), func(_ context.Context, x int) int {
+		return 2 * x
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range out {
+		if v != 2*i {
+			t.Fatalf("out[%d] = %d", i, v)
+		}
+	}
+}
+
+func TestRunEFailFast(t *testing.T) {
+	var started, cancelled atomic.Int64
+	w := New[int, int](WithWorkers(4))
+	out, err := w.RunE(context.Background(), inputsUpTo(1000), func(ctx context.Context, x int) (int, error) {
+		started.Add(1)
+		if x == 7 {
+			return 0, errOdd
+		}
+		select {
+		case <-ctx.Done():
+			cancelled.Add(1)
+			return 0, ctx.Err()
+		case <-time.After(time.Millisecond):
+			return x, nil
+		}
+	})
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Index != 7 || !errors.Is(err, errOdd) {
+		t.Fatalf("err = %v, want *TaskError for input 7 wrapping errOdd", err)
+	}
+	if out != nil {
+		t.Errorf("got results %v alongside a FailFast error", out)
+	}
+	if n := started.Load(); n == 1000 {
+		t.Error("every task ran despite FailFast")
+	}
+	if cancelled.Load() == 0 {
+		t.Error("no running task saw its context cancelled")
+	}
+}
+
+func TestRunECollectAll(t *testing.T) {
+	w := New[int, int](WithWorkers(4), WithErrorPolicy(CollectAll))
+	out, err := w.RunE(context.Background(), inputsUpTo(10), func(_ context.Context, x int) (int, error) {
+		if x%2 == 1 {
+			return 0, errOdd
+		}
+		return x, nil
+	})
+	var errs *TaskErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("err = %v, want *TaskErrors", err)
+	}
+	if len(errs.Errs) != 10 {
+		t.Fatalf("len(Errs) = %d, want one per input", len(errs.Errs))
+	}
+	for i, e := range errs.Errs {
+		var taskErr *TaskError
+		switch {
+		case i%2 == 0 && e != nil:
+			t.Errorf("Errs[%d] = %v, want nil", i, e)
+		case i%2 == 1 && (!errors.As(e, &taskErr) || taskErr.Index != i):
+			t.Errorf("Errs[%d] = %v, want *TaskError for input %d", i, e, i)
+		}
+	}
+	if len(errs.Unwrap()) != 5 || !errors.Is(err, errOdd) {
+		t.Errorf("Unwrap() = %v", errs.Unwrap())
+	}
+	for i := 0; i < 10; i += 2 {
+		if out[i] != i {
+			t.Errorf("out[%d] = %d", i, out[i])
+		}
+	}
+}
+
+func TestRunECancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := New[int, int](WithWorkers(2)).RunE(ctx, inputsUpTo(100), func(ctx context.Context, x int) (int, error) {
+		if x == 0 {
+			cancel()
+		}
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	var taskErr *TaskError
+	if errors.As(err, &taskErr) {
+		t.Errorf("caller cancellation reported as task failure %v", err)
+	}
+}
+
+func TestRunEPanic(t *testing.T) {
+	for _, policy := range []ErrorPolicy{FailFast, CollectAll} {
+		w := New[int, int](WithWorkers(4), WithErrorPolicy(policy))
+		out, err := w.RunE(context.Background(), inputsUpTo(10), func(_ context.Context, x int) (int, error) {
+			if x == 5 {
+				var m map[int]int
+				m[x] = x // nil map write
+			}
+			return x, nil
+		})
+		var panicErr *TaskPanicError
+		if !errors.As(err, &panicErr) {
+			t.Fatalf("policy %v: err = %v, want *TaskPanicError", policy, err)
+		}
+		if panicErr.Index != 5 || len(panicErr.Stack) == 0 {
+			t.Errorf("policy %v: Index = %d, len(Stack) = %d", policy, panicErr.Index, len(panicErr.Stack))
+		}
+		var runtimeErr runtime.Error
+		if !errors.As(err, &runtimeErr) {
+			t.Errorf("policy %v: %v does not unwrap to the runtime.Error", policy, err)
+		}
+		if policy == CollectAll {
+			for i, v := range out {
+				if i != 5 && v != i {
+					t.Errorf("out[%d] = %d after a recovered panic", i, v)
+				}
+			}
+		}
+	}
+}
+
+func TestRunPanicValue(t *testing.T) {
+	_, err := New[int, int](WithWorkers(2)).Run(context.Background(), inputsUpTo(4), func(_ context.Context, x int) int {
+		if x == 2 {
+			panic("boom")
+		}
+		return x
+	})
+	var panicErr *TaskPanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "boom" || panicErr.Unwrap() != nil {
+		t.Errorf("err = %v, want *TaskPanicError holding \"boom\"", err)
+	}
+}
+
+func TestRunPartialCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	partial, err := New[int, int](WithWorkers(4)).RunPartial(ctx, inputsUpTo(1000), func(ctx context.Context, x int) int {
+		switch {
+		case x == 100:
+			cancel()
+		case x > 100:
+			// Blocks until the run is cancelled
+			<-ctx.Done()
+		}
+		return 3 * x
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if len(partial.Results) != 1000 || len(partial.Done) == 0 {
+		t.Fatalf("len(Results) = %d, want a slot per input", len(partial.Results))
+	}
+	completed := 0
+	for i, v := range partial.Results {
+		if !partial.Done.Has(i) {
+			continue
+		}
+		completed++
+		if v != 3*i {
+			t.Errorf("Results[%d] = %d marked done", i, v)
+		}
+	}
+	if completed != partial.Completed() || completed < 101 || completed == 1000 {
+		t.Errorf("Completed() = %d, counted %d", partial.Completed(), completed)
+	}
+	if f := partial.Fraction(); f != float64(completed)/1000 {
+		t.Errorf("Fraction() = %v", f)
+	}
+}
+
+func TestRunPartialComplete(t *testing.T) {
+	partial, err := New[int, int](WithWorkers(4)).RunPartial(context.Background(), inputsUpTo(100), func(_ context.Context, x int) int {
+		return x
+	})
+	if err != nil || partial.Completed() != 100 || partial.Fraction() != 1 {
+		t.Errorf("Completed() = %d, err = %v", partial.Completed(), err)
+	}
+}
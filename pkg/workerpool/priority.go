@@ -0,0 +1,202 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+// PriorityPool is a long-lived set of workers like Pool, except that every
+// submission carries a priority and queued tasks are started highest
+// priority first. Tasks of equal priority start in submission order.
+type PriorityPool[T any, R any] struct {
+	PoolOptions
+	fn func(ctx context.Context, t T) (R, error)
+
+	ob       *observer
+	invalid  error // fails every submission if the options are invalid
+	start    sync.Once
+	wg       sync.WaitGroup
+	finished chan struct{}
+
+	mu     sync.Mutex
+	ready  *sync.Cond
+	queue  jobQueue[T, R]
+	closed bool
+	seq    int
+}
+
+type prioritizedJob[T any, R any] struct {
+	job[T, R]
+	priority float64
+}
+
+// jobQueue is a max-heap of jobs by priority, then by submission order.
+type jobQueue[T any, R any] []prioritizedJob[T, R]
+
+func (q jobQueue[T, R]) Len() int { return len(q) }
+
+func (q jobQueue[T, R]) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].idx < q[j].idx
+}
+
+func (q jobQueue[T, R]) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *jobQueue[T, R]) Push(x any) { *q = append(*q, x.(prioritizedJob[T, R])) }
+
+func (q *jobQueue[T, R]) Pop() any {
+	old := *q
+	n := len(old)
+	j := old[n-1]
+	old[n-1] = prioritizedJob[T, R]{}
+	*q = old[:n-1]
+	return j
+}
+
+// NewPriorityPool creates a PriorityPool that runs fn on every submitted
+// value. The pool accepts submissions right away but only executes them
+// after Start. With fewer than one worker, every submission fails with
+// ErrNoWorkers.
+func NewPriorityPool[T any, R any](fn func(ctx context.Context, t T) (R, error), opts ...PoolOptionFunc) *PriorityPool[T, R] {
+	o := defaultOpts()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	p := &PriorityPool[T, R]{
+		PoolOptions: o,
+		fn:          fn,
+		ob:          o.observe(-1),
+		invalid:     o.validate(),
+		finished:    make(chan struct{}),
+	}
+	p.ready = sync.NewCond(&p.mu)
+	return p
+}
+
+// Start launches the workers. Calling Start more than once has no effect.
+func (p *PriorityPool[T, R]) Start() {
+	p.start.Do(func() {
+		if p.invalid != nil {
+			return
+		}
+		p.logger().Debug("priority pool started", "workers", p.NumWorkers)
+		p.wg.Add(p.NumWorkers)
+		for i := 0; i < p.NumWorkers; i++ {
+			go func(id int) {
+				defer p.wg.Done()
+				_, wk := p.startWorker(context.Background(), id)
+				defer p.stopWorker(wk)
+				p.ob.workerStarted(id)
+				defer p.ob.workerStopped(id)
+				for {
+					p.ob.await(context.Background(), id)
+					j, ok := p.next()
+					if !ok {
+						return
+					}
+					j.run(p.PoolOptions, p.ob, wk, p.fn)
+				}
+			}(i)
+		}
+	})
+}
+
+// next blocks until a job is queued and returns the one with the highest
+// priority, or false once the pool is closed and drained.
+func (p *PriorityPool[T, R]) next() (job[T, R], bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.queue) == 0 && !p.closed {
+		p.ready.Wait()
+	}
+	if len(p.queue) == 0 {
+		return job[T, R]{}, false
+	}
+	return heap.Pop(&p.queue).(prioritizedJob[T, R]).job, true
+}
+
+// Submit queues t with the given priority and returns a Future for its
+// result. Higher priorities run first. The queue is unbounded, so Submit
+// never blocks; ctx is passed to fn, and the task is skipped if ctx is done
+// by the time a worker picks it up.
+//
+// fn's error is reported through the Future unchanged; a panic is reported as
+// a *TaskPanicError whose Index is the submission's sequence number. After
+// Close the Future fails immediately with ErrPoolClosed.
+func (p *PriorityPool[T, R]) Submit(ctx context.Context, t T, priority float64) *Future[R] {
+	f := newFuture[R]()
+	var zero R
+	if p.invalid != nil {
+		f.resolve(zero, p.invalid)
+		return f
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		f.resolve(zero, ErrPoolClosed)
+		return f
+	}
+
+	p.ob.enqueue(1)
+	heap.Push(&p.queue, prioritizedJob[T, R]{
+		job:      job[T, R]{ctx: ctx, idx: p.seq, input: t, queued: p.queued(), future: f},
+		priority: priority,
+	})
+	p.seq++
+	p.ready.Signal()
+	return f
+}
+
+// Close stops accepting submissions. Tasks already queued still run, in
+// priority order; use Wait to block until they have finished. If the pool
+// was never started, the queued tasks fail with ErrPoolClosed instead, and a
+// later Start has no effect.
+func (p *PriorityPool[T, R]) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	p.start.Do(func() {
+		var zero R
+		for len(p.queue) > 0 {
+			j := heap.Pop(&p.queue).(prioritizedJob[T, R])
+			p.ob.enqueue(-1)
+			j.future.resolve(zero, ErrPoolClosed)
+		}
+	})
+	p.ready.Broadcast()
+	p.ob.exhausted()
+	p.logger().Debug("priority pool closed", "submitted", p.seq)
+	go func() {
+		p.wg.Wait()
+		p.ob.close()
+		p.logger().Debug("priority pool stopped")
+		close(p.finished)
+	}()
+}
+
+// Wait blocks until every worker has exited, which happens once the pool
+// has been closed and its queue drained.
+func (p *PriorityPool[T, R]) Wait() {
+	<-p.finished
+}
@@ -0,0 +1,48 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPriorityPoolCloseBeforeStart(t *testing.T) {
+	p := NewPriorityPool(square, WithWorkers(1))
+	f := p.Submit(context.Background(), 2, 1)
+	p.Close()
+	p.Wait()
+	if _, err := get(t, f); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("err = %v, want ErrPoolClosed", err)
+	}
+}
+
+func TestPriorityPoolRunsByPriority(t *testing.T) {
+	var order []int
+	p := NewPriorityPool(func(_ context.Context, x int) (int, error) {
+		order = append(order, x)
+		return x, nil
+	}, WithWorkers(1))
+	for i, prio := range []float64{1, 3, 2} {
+		p.Submit(context.Background(), i, prio)
+	}
+	p.Start()
+	p.Close()
+	p.Wait()
+	if want := []int{1, 2, 0}; len(order) != 3 || order[0] != want[0] || order[1] != want[1] || order[2] != want[2] {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Progress is a snapshot of a running pool reported to the WithProgress
+// callback.
+type Progress struct {
+	// Completed is the number of tasks that have finished, including
+	// Failed ones.
+	Completed int
+	Failed    int
+	// Total is the number of tasks in the run, or -1 for streams whose
+	// length is unknown.
+	Total   int
+	Elapsed time.Duration
+	// Throughput is the number of completed tasks per second.
+	Throughput float64
+	// ETA estimates the time left from the current throughput. It is zero
+	// when no estimate is available.
+	ETA time.Duration
+	// Busy is the time each worker has spent running tasks.
+	Busy []time.Duration
+	// Done is set on the final report, sent once the run has ended.
+	Done bool
+}
+
+// Fraction returns the completed share of the tasks in [0, 1], or 0 if the
+// total is unknown.
+func (p Progress) Fraction() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	return float64(p.Completed) / float64(p.Total)
+}
+
+// WithProgress calls fn with a Progress snapshot at every ProgressInterval
+// while a run or stream is in progress, and once more when it ends. fn is
+// never called concurrently.
+func WithProgress(fn func(Progress)) PoolOptionFunc {
+	return func(opts *PoolOptions) {
+		opts.Progress = fn
+	}
+}
+
+// WithProgressInterval sets how often WithProgress reports. The default is
+// one second.
+func WithProgressInterval(d time.Duration) PoolOptionFunc {
+	return func(opts *PoolOptions) {
+		opts.ProgressInterval = d
+	}
+}
+
+// progress tracks the tasks of one run and reports them periodically. A nil
+// *progress is valid and ignores all events.
+type progress struct {
+	fn        func(Progress)
+	total     int
+	start     time.Time
+	completed atomic.Int64
+	failed    atomic.Int64
+	busy      []atomic.Int64 // nanoseconds per worker
+	stop      chan struct{}
+	stopped   chan struct{}
+}
+
+// trackProgress starts reporting on a run of total tasks, or returns nil if
+// no progress callback is configured.
+func (o PoolOptions) trackProgress(total int) *progress {
+	if o.Progress == nil {
+		return nil
+	}
+	interval := o.ProgressInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	p := &progress{
+		fn:      o.Progress,
+		total:   total,
+		start:   time.Now(),
+		busy:    make([]atomic.Int64, o.NumWorkers),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go func() {
+		defer close(p.stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				p.fn(p.snapshot(true))
+				return
+			case <-ticker.C:
+				p.fn(p.snapshot(false))
+			}
+		}
+	}()
+	return p
+}
+
+// taskDone records a task that kept worker busy for the given time.
+func (p *progress) taskDone(worker int, busy time.Duration, err error) {
+	if p == nil {
+		return
+	}
+	p.busy[worker].Add(int64(busy))
+	if err != nil {
+		p.failed.Add(1)
+	}
+	p.completed.Add(1)
+}
+
+// copyDropped adds the time worker spent on a discarded copy of a hedged
+// task to its busy time.
+func (p *progress) copyDropped(worker int, busy time.Duration) {
+	if p == nil {
+		return
+	}
+	p.busy[worker].Add(int64(busy))
+}
+
+// close sends the final report and waits for it to be delivered.
+func (p *progress) close() {
+	if p == nil {
+		return
+	}
+	close(p.stop)
+	<-p.stopped
+}
+
+func (p *progress) snapshot(done bool) Progress {
+	s := Progress{
+		Completed: int(p.completed.Load()),
+		Failed:    int(p.failed.Load()),
+		Total:     p.total,
+		Elapsed:   time.Since(p.start),
+		Busy:      make([]time.Duration, len(p.busy)),
+		Done:      done,
+	}
+	for i := range p.busy {
+		s.Busy[i] = time.Duration(p.busy[i].Load())
+	}
+	if secs := s.Elapsed.Seconds(); secs > 0 {
+		s.Throughput = float64(s.Completed) / secs
+	}
+	if s.Total > 0 && s.Throughput > 0 && !done {
+		remaining := float64(s.Total - s.Completed)
+		s.ETA = time.Duration(remaining / s.Throughput * float64(time.Second))
+	}
+	return s
+}
+
+// ProgressBar returns a WithProgress callback that draws a single-line
+// progress bar on w, typically os.Stderr, redrawing it in place on every
+// report and ending the line on the final one.
+func ProgressBar(w io.Writer) func(Progress) {
+	const width = 30
+	return func(p Progress) {
+		var line string
+		if p.Total >= 0 {
+			filled := int(p.Fraction() * width)
+			bar := strings.Repeat("=", filled) + strings.Repeat(" ", width-filled)
+			line = fmt.Sprintf("[%s] %5.1f%% %d/%d", bar, 100*p.Fraction(), p.Completed, p.Total)
+		} else {
+			line = fmt.Sprintf("%d tasks", p.Completed)
+		}
+		line += fmt.Sprintf("  %.1f tasks/s", p.Throughput)
+		if p.Failed > 0 {
+			line += fmt.Sprintf("  %d failed", p.Failed)
+		}
+		if p.Done {
+			line += fmt.Sprintf("  done in %s", p.Elapsed.Round(time.Millisecond))
+		} else if p.ETA > 0 {
+			line += fmt.Sprintf("  ETA %s", p.ETA.Round(time.Second))
+		}
+		// Pad over the remains of a longer previous line
+		fmt.Fprintf(w, "\r%-80s", line)
+		if p.Done {
+			fmt.Fprintln(w)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy describes how failed tasks are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts per task, including the
+	// first one. Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the second attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts. Zero means no cap.
+	MaxBackoff time.Duration
+	// Multiplier scales the delay after every attempt. Zero selects 2.
+	Multiplier float64
+	// Jitter randomises each delay by up to this fraction in either
+	// direction, in [0, 1], so that failing tasks do not retry in lockstep.
+	Jitter float64
+	// Retryable reports whether err is worth retrying. Nil retries every
+	// error. Panics are never retried, and neither is anything once the run
+	// itself has been cancelled.
+	Retryable func(err error) bool
+}
+
+// backoff returns the delay to wait after the given failed attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	mult := p.Multiplier
+	if mult == 0 {
+		mult = 2
+	}
+	d := float64(p.InitialBackoff) * math.Pow(mult, float64(attempt-1))
+	if p.Jitter > 0 {
+		d *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	// Cap after jittering, so that no delay exceeds MaxBackoff
+	if p.MaxBackoff > 0 {
+		d = math.Min(d, float64(p.MaxBackoff))
+	}
+	return time.Duration(d)
+}
+
+// retryable reports whether a task that failed with err on the given
+// attempt should run again.
+func (p RetryPolicy) retryable(ctx context.Context, attempt int, err error) bool {
+	if attempt >= p.MaxAttempts || ctx.Err() != nil {
+		return false
+	}
+	if _, ok := err.(*TaskPanicError); ok {
+		return false
+	}
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+// sleep waits for d or until ctx is done, and reports whether the full
+// delay elapsed.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		policy RetryPolicy
+		want   []time.Duration // by attempt, from 1
+	}{
+		{RetryPolicy{InitialBackoff: 10 * time.Millisecond},
+			[]time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond}},
+		{RetryPolicy{InitialBackoff: time.Millisecond, Multiplier: 3},
+			[]time.Duration{time.Millisecond, 3 * time.Millisecond, 9 * time.Millisecond}},
+		{RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 25 * time.Millisecond},
+			[]time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 25 * time.Millisecond, 25 * time.Millisecond}},
+	}
+	for _, tt := range tests {
+		for i, want := range tt.want {
+			if got := tt.policy.backoff(i + 1); got != want {
+				t.Errorf("%+v: backoff(%d) = %v, want %v", tt.policy, i+1, got, want)
+			}
+		}
+	}
+}
+
+func TestRetryBackoffJitter(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, Jitter: 0.5}
+	capped := p
+	capped.MaxBackoff = 100 * time.Millisecond
+	for i := 0; i < 1000; i++ {
+		if d := p.backoff(1); d < 50*time.Millisecond || d > 150*time.Millisecond {
+			t.Fatalf("backoff(1) = %v, want within 50%% of 100ms", d)
+		}
+		// The cap holds after jittering
+		if d := capped.backoff(3); d > capped.MaxBackoff {
+			t.Fatalf("backoff(3) = %v exceeds MaxBackoff %v", d, capped.MaxBackoff)
+		}
+	}
+}
+
+func TestRetryAttempts(t *testing.T) {
+	errFlaky := errors.New("flaky")
+	errFatal := errors.New("fatal")
+	var calls [4]atomic.Int64
+	w := New[int, int](WithWorkers(2), WithErrorPolicy(CollectAll), WithRetry(RetryPolicy{
+		MaxAttempts: 3,
+		Retryable:   func(err error) bool { return !errors.Is(err, errFatal) },
+	}))
+	results, err := w.RunResults(context.Background(), inputsUpTo(4), func(_ context.Context, x int) (int, error) {
+		n := calls[x].Add(1)
+		switch x {
+		case 1:
+			// Succeeds on the last attempt
+			if n < 3 {
+				return 0, errFlaky
+			}
+		case 2:
+			return 0, errFlaky
+		case 3:
+			return 0, errFatal
+		}
+		return x, nil
+	})
+	if err == nil {
+		t.Fatal("expected the failures of inputs 2 and 3")
+	}
+	want := []struct {
+		attempts int
+		err      error
+	}{{1, nil}, {3, nil}, {3, errFlaky}, {1, errFatal}}
+	for i, r := range results {
+		if r.Attempts != want[i].attempts || !errors.Is(r.Err, want[i].err) || (want[i].err == nil) != (r.Err == nil) {
+			t.Errorf("input %d: Attempts = %d, Err = %v; want %d, %v", i, r.Attempts, r.Err, want[i].attempts, want[i].err)
+		}
+	}
+}
+
+func TestRetryNotAfterPanic(t *testing.T) {
+	var calls atomic.Int64
+	w := New[int, int](WithWorkers(1), WithRetry(RetryPolicy{MaxAttempts: 3}))
+	results, _ := w.RunResults(context.Background(), inputsUpTo(1), func(context.Context, int) (int, error) {
+		calls.Add(1)
+		panic("boom")
+	})
+	if calls.Load() != 1 || results[0].Attempts != 1 {
+		t.Errorf("panicking task ran %d times, Attempts = %d; want 1", calls.Load(), results[0].Attempts)
+	}
+}
+
+func TestTaskTimeout(t *testing.T) {
+	var calls atomic.Int64
+	w := New[int, int](WithWorkers(1), WithErrorPolicy(CollectAll), WithTaskTimeout(10*time.Millisecond),
+		WithRetry(RetryPolicy{MaxAttempts: 2}))
+	results, err := w.RunResults(context.Background(), inputsUpTo(2), func(ctx context.Context, x int) (int, error) {
+		if x == 1 && calls.Add(1) == 1 {
+			// Only the first attempt hangs; the retry gets a fresh deadline
+			<-ctx.Done()
+			return 0, ctx.Err()
+		}
+		if x == 0 {
+			<-ctx.Done()
+			return 0, ctx.Err()
+		}
+		return x, nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if r := results[0]; r.Attempts != 2 || !errors.Is(r.Err, context.DeadlineExceeded) {
+		t.Errorf("input 0: Attempts = %d, Err = %v; want 2 timeouts", r.Attempts, r.Err)
+	}
+	if r := results[1]; r.Attempts != 2 || r.Err != nil || r.Value != 1 {
+		t.Errorf("input 1: %+v, want success on attempt 2", r)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import "sync/atomic"
+
+type scheduleKind int
+
+const (
+	dynamic scheduleKind = iota
+	static
+	guided
+)
+
+// Schedule decides how input indices are split into chunks and handed out
+// to workers, following the OpenMP loop schedules of the same name.
+type Schedule struct {
+	kind  scheduleKind
+	chunk int
+}
+
+// Static splits the inputs into one contiguous block per worker up front.
+// It has the lowest overhead but only balances uniform workloads.
+var Static = Schedule{kind: static}
+
+// Dynamic hands out chunks of a fixed size to whichever worker is free.
+// Dynamic(1), the default, dispatches inputs one at a time.
+func Dynamic(chunk int) Schedule {
+	return Schedule{kind: dynamic, chunk: max(chunk, 1)}
+}
+
+// Guided hands out chunks proportional to the remaining work divided by the
+// number of workers, shrinking towards min as the run progresses. It suits
+// uneven workloads without having to tune a chunk size.
+func Guided(min int) Schedule {
+	return Schedule{kind: guided, chunk: max(min, 1)}
+}
+
+// scheduler tracks the chunks handed out during a single run.
+type scheduler struct {
+	Schedule
+	n       int
+	workers int
+	next    atomic.Int64
+	taken   []bool // per worker, Static only
+}
+
+func (s Schedule) start(n, workers int) *scheduler {
+	sc := &scheduler{Schedule: s, n: n, workers: workers}
+	if sc.chunk < 1 {
+		sc.chunk = 1
+	}
+	if s.kind == static {
+		sc.taken = make([]bool, workers)
+	}
+	return sc
+}
+
+// take returns the next range [lo, hi) of indices for worker, or false once
+// the worker has nothing left to do.
+func (s *scheduler) take(worker int) (lo, hi int, ok bool) {
+	switch s.kind {
+	case static:
+		// Each worker owns taken[worker], so no synchronisation is needed
+		if s.taken[worker] {
+			return 0, 0, false
+		}
+		s.taken[worker] = true
+		lo = worker * s.n / s.workers
+		hi = (worker + 1) * s.n / s.workers
+		return lo, hi, lo < hi
+	case guided:
+		for {
+			next := s.next.Load()
+			remaining := int64(s.n) - next
+			if remaining <= 0 {
+				return 0, 0, false
+			}
+			size := max((remaining+int64(s.workers)-1)/int64(s.workers), int64(s.chunk))
+			size = min(size, remaining)
+			if s.next.CompareAndSwap(next, next+size) {
+				return int(next), int(next + size), true
+			}
+		}
+	default:
+		chunk := int64(s.chunk)
+		next := s.next.Add(chunk) - chunk
+		if next >= int64(s.n) {
+			return 0, 0, false
+		}
+		return int(next), int(min(next+chunk, int64(s.n))), true
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"iter"
+	"sync"
+	"time"
+)
+
+// Result is the outcome of a single task together with its metadata.
+type Result[R any] struct {
+	// Index is the position of the input in the slice or stream.
+	Index int
+	Value R
+	Err   error
+	// Attempts is the number of times the task was run, which exceeds 1
+	// when it was retried, and is 0 if it never ran.
+	Attempts int
+}
+
+// RunStream applies fn to every value produced by inputs and emits the
+// results on the returned channel, which is closed once the stream is
+// exhausted and all workers have exited.
+//
+// At most BufferSize inputs are pulled ahead of the consumer, so memory stays
+// bounded however long the stream is. With Ordered set, results are emitted
+// in input order; otherwise they are emitted as soon as they complete.
+//
+// Failed tasks are emitted with Err set to a *TaskError or *TaskPanicError.
+// Under FailFast the stream stops after the first failure; under CollectAll it
+// keeps going. When ctx is cancelled the stream stops early and the channel is
+// closed, so callers should check ctx.Err() after draining it.
+//
+// With fewer than one worker, the channel carries a single Result with Index
+// -1 and Err set to ErrNoWorkers, and no input is pulled.
+func (w *WorkerPoolExecutor[T, R]) RunStream(ctx context.Context, inputs iter.Seq[T], fn func(ctx context.Context, t T) (R, error)) <-chan Result[R] {
+	return w.stream(ctx, func(context.Context) iter.Seq[T] { return inputs }, fn)
+}
+
+// stream implements RunStream. source is called with the context of the
+// stream, which is cancelled as soon as the stream stops, so that sources
+// that block waiting for input can give up.
+func (w *WorkerPoolExecutor[T, R]) stream(ctx context.Context, source func(ctx context.Context) iter.Seq[T], fn func(ctx context.Context, t T) (R, error)) <-chan Result[R] {
+	if err := w.validate(); err != nil {
+		out := make(chan Result[R], 1)
+		out <- Result[R]{Index: -1, Err: err}
+		close(out)
+		return out
+	}
+	ctx, span := w.startRun(ctx, -1)
+	w.logger().Debug("stream started", "workers", w.NumWorkers)
+	start, parent := time.Now(), ctx
+	ctx, cancel := context.WithCancel(ctx)
+
+	type task struct {
+		idx    int
+		input  T
+		queued time.Time
+	}
+
+	size := w.bufferSize()
+	ob := w.observe(-1)
+	// Each slot is held from the moment an input is pulled until its result
+	// has been handed to the consumer.
+	slots := make(chan struct{}, size)
+	// Buffered so that the queue depth reflects the backlog of inputs
+	tasks := make(chan task, size)
+	results := make(chan Result[R], size)
+	out := make(chan Result[R], size)
+
+	var wg sync.WaitGroup
+	wg.Add(w.NumWorkers)
+	for i := 0; i < w.NumWorkers; i++ {
+		go func(id int) {
+			defer wg.Done()
+			ctx, wk := w.startWorker(ctx, id)
+			defer w.stopWorker(wk)
+			ob.workerStarted(id)
+			defer ob.workerStopped(id)
+			for {
+				ob.await(ctx, id)
+				t, ok := <-tasks
+				if !ok {
+					return
+				}
+				start := ob.taskStarted(id)
+				value, attempts, err := call(w.PoolOptions, ctx, wk, TaskInfo{Index: t.idx, Worker: id, Queued: t.queued}, t.input, fn)
+				ob.taskFinished(id, start, err)
+				if err != nil {
+					err = wrapTaskError(t.idx, err)
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case results <- Result[R]{Index: t.idx, Value: value, Err: err, Attempts: attempts}:
+				}
+			}
+		}(i)
+	}
+
+	// Pull inputs only as fast as slots free up
+	go func() {
+		defer ob.exhausted()
+		defer close(tasks)
+		idx := 0
+		for input := range source(ctx) {
+			select {
+			case <-ctx.Done():
+				return
+			case slots <- struct{}{}:
+			}
+			ob.enqueue(1)
+			select {
+			case <-ctx.Done():
+				ob.enqueue(-1)
+				return
+			case tasks <- task{idx: idx, input: input, queued: w.queued()}:
+			}
+			idx++
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		ob.close()
+		w.logEnd("stream", start, parent.Err())
+		span.End(parent.Err())
+		close(results)
+	}()
+
+	go func() {
+		defer close(out)
+		defer cancel()
+
+		emit := func(r Result[R]) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case out <- r:
+			}
+			<-slots
+			if r.Err != nil && w.ErrorPolicy == FailFast {
+				cancel()
+				return false
+			}
+			return true
+		}
+
+		// Keep draining after a stop so that no worker blocks on send
+		stopped := false
+		next := 0
+		pending := make(map[int]Result[R])
+		for r := range results {
+			if stopped {
+				continue
+			}
+			if !w.Ordered {
+				stopped = !emit(r)
+				continue
+			}
+			pending[r.Index] = r
+			for !stopped {
+				r, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+				stopped = !emit(r)
+			}
+		}
+	}()
+
+	return out
+}
+
+// RunChan is like RunStream but reads its inputs from a channel until it is
+// closed or ctx is cancelled.
+func (w *WorkerPoolExecutor[T, R]) RunChan(ctx context.Context, inputs <-chan T, fn func(ctx context.Context, t T) (R, error)) <-chan Result[R] {
+	return w.stream(ctx, func(ctx context.Context) iter.Seq[T] {
+		return func(yield func(T) bool) {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case t, ok := <-inputs:
+					if !ok || !yield(t) {
+						return
+					}
+				}
+			}
+		}
+	}, fn)
+}
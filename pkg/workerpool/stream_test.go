@@ -0,0 +1,62 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestRunStreamOrdered(t *testing.T) {
+	w := New[int, int](WithWorkers(4), WithOrdered(), WithBuffer(2))
+	var got []int
+	for r := range w.RunStream(context.Background(), slices.Values([]int{1, 2, 3, 4, 5, 6, 7, 8}), square) {
+		if r.Err != nil {
+			t.Fatal(r.Err)
+		}
+		got = append(got, r.Value)
+	}
+	if want := []int{1, 4, 9, 16, 25, 36, 49, 64}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRunStreamNoWorkers(t *testing.T) {
+	for _, workers := range []int{0, -1} {
+		w := New[int, int](WithWorkers(workers))
+		pulled := false
+		inputs := func(yield func(int) bool) {
+			pulled = true
+		}
+		var results []Result[int]
+		for r := range w.RunStream(context.Background(), inputs, square) {
+			results = append(results, r)
+		}
+		if len(results) != 1 || results[0].Index != -1 || !errors.Is(results[0].Err, ErrNoWorkers) || pulled {
+			t.Errorf("RunStream with %d workers: got %+v, pulled %v", workers, results, pulled)
+		}
+
+		ch := make(chan int)
+		results = results[:0]
+		for r := range w.RunChan(context.Background(), ch, square) {
+			results = append(results, r)
+		}
+		if len(results) != 1 || !errors.Is(results[0].Err, ErrNoWorkers) {
+			t.Errorf("RunChan with %d workers: got %+v", workers, results)
+		}
+	}
+}
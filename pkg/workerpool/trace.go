@@ -0,0 +1,84 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"time"
+)
+
+// Tracer wraps runs and task attempts in spans. Every run of a
+// WorkerPoolExecutor gets a run span, and every attempt at a task gets a task
+// span whose context is derived from the run's. Tasks of a Pool have no run;
+// their spans are children of the context passed to Submit.
+//
+// Implementations must be safe for concurrent use. The pkg/tracing package
+// provides a Chrome trace-event writer and an OpenTelemetry adapter.
+type Tracer interface {
+	// StartRun opens the span of a run of total tasks, or -1 for streams
+	// whose length is unknown.
+	StartRun(ctx context.Context, total int) (context.Context, Span)
+	// StartTask opens the span of a single attempt at a task. It is called
+	// on the worker goroutine right before the task function runs.
+	StartTask(ctx context.Context, task TaskInfo) (context.Context, Span)
+}
+
+// Span is an open span returned by a Tracer.
+type Span interface {
+	// End closes the span. err is the outcome of the run or attempt.
+	End(err error)
+}
+
+// TaskInfo describes a task attempt to a Tracer.
+type TaskInfo struct {
+	// Index is the position of the input in the slice or stream, or the
+	// sequence number of a submission to a Pool.
+	Index   int
+	Worker  int
+	Attempt int
+	// Queued is when the attempt became ready to run: the start of the run
+	// for slices, when the input was pulled for streams, the call to Submit
+	// for a Pool, and the failure of the previous attempt for retries. The
+	// time between Queued and the start of the span is the queue wait.
+	Queued time.Time
+}
+
+// WithTracer wraps every run and task attempt in spans created by t.
+func WithTracer(t Tracer) PoolOptionFunc {
+	return func(opts *PoolOptions) {
+		opts.Tracer = t
+	}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) End(error) {}
+
+// startRun opens the span of a run if a tracer is configured.
+func (o PoolOptions) startRun(ctx context.Context, total int) (context.Context, Span) {
+	if o.Tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return o.Tracer.StartRun(ctx, total)
+}
+
+// queued returns the current time if a tracer needs it, and the zero time
+// otherwise.
+func (o PoolOptions) queued() time.Time {
+	if o.Tracer == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
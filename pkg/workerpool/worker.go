@@ -0,0 +1,175 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"math/rand/v2"
+
+	"github.com/qcserestipy/gohpc/pkg/rng"
+)
+
+// worker is the per-goroutine state of a pool worker. It is attached once to
+// the context the worker passes to every task it runs.
+type worker struct {
+	id    int
+	state any
+	unpin func()
+
+	// task is the index of the input being processed. The generator behind
+	// Rand is reseeded for it lazily, on the first call within an attempt.
+	task    int
+	attempt int
+	seed    uint64
+	seeded  bool
+	src     rng.Xoshiro256
+	rand    *rand.Rand
+}
+
+// begin prepares the worker for an attempt at the task for input idx.
+func (w *worker) begin(idx, attempt int) {
+	w.task = idx
+	w.attempt = attempt
+	w.seeded = false
+}
+
+// nest prepares the worker for a task started while the current one waits,
+// as in a fork-join Sync, and returns the function that resumes the current
+// task with its generator where it left off.
+func (w *worker) nest(idx int) (resume func()) {
+	task, attempt, seeded, src := w.task, w.attempt, w.seeded, w.src
+	w.begin(idx, 1)
+	return func() {
+		w.task, w.attempt, w.seeded, w.src = task, attempt, seeded, src
+	}
+}
+
+type workerKey struct{}
+
+// WithWorkerInit registers a function that creates worker-local state once
+// per worker goroutine, before it runs its first task. Tasks retrieve the
+// state with WorkerState, which makes it the place for scratch buffers, RNGs
+// and other resources that are expensive to allocate per task.
+//
+// Worker hooks apply to Run, RunE, RunPartial, RunStream, RunChan, MapReduce,
+// ParallelFor, ForkJoinExecutor, Pool and PriorityPool.
+func WithWorkerInit[S any](init func(workerID int) S) PoolOptionFunc {
+	return func(opts *PoolOptions) {
+		opts.WorkerInit = func(workerID int) any {
+			return init(workerID)
+		}
+	}
+}
+
+// WithWorkerTeardown registers a function that is called with the
+// worker-local state when a worker goroutine exits.
+func WithWorkerTeardown[S any](teardown func(workerID int, state S)) PoolOptionFunc {
+	return func(opts *PoolOptions) {
+		opts.WorkerTeardown = func(workerID int, state any) {
+			s, _ := state.(S)
+			teardown(workerID, s)
+		}
+	}
+}
+
+// startWorker pins worker id to its CPU, runs the init hook and returns the
+// context its tasks should receive.
+func (o PoolOptions) startWorker(ctx context.Context, id int) (context.Context, *worker) {
+	// Pin first so that the init hook allocates on the worker's NUMA node
+	w := &worker{id: id, seed: o.Seed, unpin: o.pin(id)}
+	if o.WorkerInit != nil {
+		w.state = o.WorkerInit(id)
+	}
+	return w.attach(ctx), w
+}
+
+// attach returns a copy of ctx carrying w, for tasks whose context comes
+// from their submitter rather than from the worker.
+func (w *worker) attach(ctx context.Context) context.Context {
+	return context.WithValue(ctx, workerKey{}, w)
+}
+
+// stopWorker runs the teardown hook for w and releases its CPU.
+func (o PoolOptions) stopWorker(w *worker) {
+	if o.WorkerTeardown != nil {
+		o.WorkerTeardown(w.id, w.state)
+	}
+	w.unpin()
+}
+
+// WorkerID returns the ID of the worker running the current task, in
+// [0, NumWorkers), or -1 if ctx was not passed in by a pool worker.
+func WorkerID(ctx context.Context) int {
+	if w, ok := ctx.Value(workerKey{}).(*worker); ok {
+		return w.id
+	}
+	return -1
+}
+
+// WorkerState returns the state created by WithWorkerInit for the worker
+// running the current task. It returns the zero value if there is none or
+// it is not of type S.
+func WorkerState[S any](ctx context.Context) S {
+	w, _ := ctx.Value(workerKey{}).(*worker)
+	if w == nil {
+		var zero S
+		return zero
+	}
+	s, _ := w.state.(S)
+	return s
+}
+
+// Attempt returns the 1-based attempt number of the current task, which is
+// greater than 1 when the task is being retried. It returns 0 if ctx was not
+// passed in by a pool worker.
+func Attempt(ctx context.Context) int {
+	if w, ok := ctx.Value(workerKey{}).(*worker); ok {
+		return w.attempt
+	}
+	return 0
+}
+
+// Rand returns a random number generator for the current task. Its sequence
+// is determined by the pool's Seed and the index of the task's input alone,
+// so a run with a fixed seed produces bit-identical results regardless of
+// the number of workers or the schedule. In a Pool or PriorityPool the index
+// is the submission's sequence number.
+//
+// The generator belongs to the worker and is reset for every task attempt, so
+// a retried task draws the same numbers again. It must not be retained or
+// shared with other goroutines. If ctx was not passed in by a pool worker,
+// Rand returns a new randomly seeded generator.
+func Rand(ctx context.Context) *rand.Rand {
+	w, _ := ctx.Value(workerKey{}).(*worker)
+	if w == nil {
+		return rand.New(rng.New(rand.Uint64()))
+	}
+	if w.rand == nil {
+		w.rand = rand.New(&w.src)
+	}
+	if !w.seeded {
+		w.src.SeedStream(w.seed, uint64(w.task))
+		w.seeded = true
+	}
+	return w.rand
+}
+
+// RunWithState is like Run for task functions that take the worker-local
+// state created by WithWorkerInit as an explicit argument.
+func RunWithState[T any, R any, S any](ctx context.Context, w *WorkerPoolExecutor[T, R], inputs []T, fn func(ctx context.Context, state S, t T) R) ([]R, error) {
+	return w.Run(ctx, inputs, func(ctx context.Context, t T) R {
+		return fn(ctx, WorkerState[S](ctx), t)
+	})
+}
@@ -0,0 +1,95 @@
+// Copyright Project GoHPC Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// draws sums a task-dependent number of values from Rand, so that workers
+// consume their generators at different rates.
+func draws(ctx context.Context, x int) uint64 {
+	r := Rand(ctx)
+	var sum uint64
+	for i := 0; i <= x%7; i++ {
+		sum += r.Uint64()
+	}
+	return sum
+}
+
+func TestRandReproducible(t *testing.T) {
+	inputs := inputsUpTo(200)
+	want, err := New[int, uint64](WithWorkers(1), WithSeed(42)).Run(context.Background(), inputs, draws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schedules := map[string]Schedule{
+		"dynamic": Dynamic(1),
+		"chunked": Dynamic(16),
+		"static":  Static,
+		"guided":  Guided(2),
+	}
+	for _, workers := range []int{2, 3, 8} {
+		for name, schedule := range schedules {
+			t.Run(fmt.Sprintf("%s/%d", name, workers), func(t *testing.T) {
+				got, err := New[int, uint64](WithWorkers(workers), WithSchedule(schedule), WithSeed(42)).
+					Run(context.Background(), inputs, draws)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !slices.Equal(got, want) {
+					t.Error("results differ from the single-worker run")
+				}
+			})
+		}
+	}
+
+	other, _ := New[int, uint64](WithWorkers(4), WithSeed(43)).Run(context.Background(), inputs, draws)
+	if slices.Equal(other, want) {
+		t.Error("a different seed gives the same results")
+	}
+}
+
+func TestRandRetry(t *testing.T) {
+	// A retried attempt draws the same numbers as a first one
+	var (
+		mu    sync.Mutex
+		tried = make(map[int]bool)
+	)
+	inputs := inputsUpTo(50)
+	want, _ := New[int, uint64](WithWorkers(4), WithSeed(7)).Run(context.Background(), inputs, draws)
+	got, err := New[int, uint64](WithWorkers(4), WithSeed(7), WithRetry(RetryPolicy{MaxAttempts: 2})).
+		RunE(context.Background(), inputs, func(ctx context.Context, x int) (uint64, error) {
+			v := draws(ctx, x)
+			mu.Lock()
+			defer mu.Unlock()
+			if x%3 == 0 && !tried[x] {
+				tried[x] = true
+				return 0, errors.New("retry")
+			}
+			return v, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, want) {
+		t.Error("retried tasks drew different numbers")
+	}
+}